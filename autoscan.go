@@ -16,6 +16,10 @@ type Scan struct {
 	Folder   string
 	Priority int
 	Time     time.Time
+
+	// Origin is the ID of the media server the scan originates from.
+	// The scan is not sent to the target of the same media server.
+	Origin string
 }
 
 type ProcessorFunc func(...Scan) error
@@ -35,6 +39,20 @@ type Target interface {
 	Available() error
 }
 
+// A LibraryTarget is a Target which exposes the root folders of its libraries.
+// The folders are paths on the file system of the target.
+type LibraryTarget interface {
+	Target
+	Libraries() []string
+}
+
+// An IdentifiedTarget is a Target which exposes the ID of its media server.
+// Scans with the same Origin are not sent to the target.
+type IdentifiedTarget interface {
+	Target
+	ID() string
+}
+
 var (
 	// ErrTargetUnavailable may occur when a Target goes offline
 	// or suffers from fatal errors. In this case, the processor
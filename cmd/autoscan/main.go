@@ -25,13 +25,26 @@ import (
 	"github.com/cloudbox/autoscan/targets/jellyfin"
 	"github.com/cloudbox/autoscan/targets/plex"
 	"github.com/cloudbox/autoscan/triggers/a_train"
+	"github.com/cloudbox/autoscan/triggers/bazarr"
 	"github.com/cloudbox/autoscan/triggers/bernard"
+	"github.com/cloudbox/autoscan/triggers/fanotify"
+	"github.com/cloudbox/autoscan/triggers/history"
 	"github.com/cloudbox/autoscan/triggers/inotify"
+	jellyfinhook "github.com/cloudbox/autoscan/triggers/jellyfin"
 	"github.com/cloudbox/autoscan/triggers/lidarr"
 	"github.com/cloudbox/autoscan/triggers/manual"
+	"github.com/cloudbox/autoscan/triggers/mqtt"
+	"github.com/cloudbox/autoscan/triggers/nats"
+	plexhook "github.com/cloudbox/autoscan/triggers/plex"
+	"github.com/cloudbox/autoscan/triggers/poll"
 	"github.com/cloudbox/autoscan/triggers/radarr"
+	"github.com/cloudbox/autoscan/triggers/rclone"
 	"github.com/cloudbox/autoscan/triggers/readarr"
+	"github.com/cloudbox/autoscan/triggers/s3"
+	"github.com/cloudbox/autoscan/triggers/schedule"
 	"github.com/cloudbox/autoscan/triggers/sonarr"
+	"github.com/cloudbox/autoscan/triggers/spool"
+	"github.com/cloudbox/autoscan/triggers/webhook"
 
 	// sqlite3 driver
 	_ "modernc.org/sqlite"
@@ -54,14 +67,28 @@ type config struct {
 
 	// autoscan.HTTPTrigger
 	Triggers struct {
-		Manual  manual.Config    `yaml:"manual"`
-		ATrain  a_train.Config   `yaml:"a-train"`
-		Bernard []bernard.Config `yaml:"bernard"`
-		Inotify []inotify.Config `yaml:"inotify"`
-		Lidarr  []lidarr.Config  `yaml:"lidarr"`
-		Radarr  []radarr.Config  `yaml:"radarr"`
-		Readarr []readarr.Config `yaml:"readarr"`
-		Sonarr  []sonarr.Config  `yaml:"sonarr"`
+		Manual   manual.Config         `yaml:"manual"`
+		ATrain   a_train.Config        `yaml:"a-train"`
+		Bazarr   []bazarr.Config       `yaml:"bazarr"`
+		Bernard  []bernard.Config      `yaml:"bernard"`
+		Emby     []jellyfinhook.Config `yaml:"emby"`
+		Fanotify []fanotify.Config     `yaml:"fanotify"`
+		History  []history.Config      `yaml:"history"`
+		Inotify  []inotify.Config      `yaml:"inotify"`
+		Jellyfin []jellyfinhook.Config `yaml:"jellyfin"`
+		Lidarr   []lidarr.Config       `yaml:"lidarr"`
+		MQTT     []mqtt.Config         `yaml:"mqtt"`
+		NATS     []nats.Config         `yaml:"nats"`
+		Plex     []plexhook.Config     `yaml:"plex"`
+		Poll     []poll.Config         `yaml:"poll"`
+		Radarr   []radarr.Config       `yaml:"radarr"`
+		Rclone   []rclone.Config       `yaml:"rclone"`
+		Readarr  []readarr.Config      `yaml:"readarr"`
+		S3       []s3.Config           `yaml:"s3"`
+		Schedule []schedule.Config     `yaml:"schedule"`
+		Sonarr   []sonarr.Config       `yaml:"sonarr"`
+		Spool    []spool.Config        `yaml:"spool"`
+		Webhook  []webhook.Config      `yaml:"webhook"`
 	} `yaml:"triggers"`
 
 	// autoscan.Target
@@ -88,6 +115,13 @@ var (
 		Database  string `type:"path" default:"${database_file}" env:"AUTOSCAN_DATABASE" help:"Database file path"`
 		Log       string `type:"path" default:"${log_file}" env:"AUTOSCAN_LOG" help:"Log file path"`
 		Verbosity int    `type:"counter" default:"0" short:"v" env:"AUTOSCAN_VERBOSITY" help:"Log level verbosity"`
+
+		// commands
+		Run    struct{} `cmd:"" default:"1" help:"Run autoscan (default)"`
+		Resync struct {
+			Drive string `arg:"" help:"ID of the Bernard drive"`
+			Scan  bool   `help:"Scan the root of the drive once resynced"`
+		} `cmd:"" help:"Fully resync a Bernard drive on the next run"`
 	}
 )
 
@@ -161,6 +195,30 @@ func main() {
 	}
 	db.SetMaxOpenConns(1)
 
+	// migrator
+	mg, err := migrate.New(db, "migrations")
+	if err != nil {
+		log.Fatal().
+			Err(err).
+			Msg("Failed initialising migrator")
+	}
+
+	// commands
+	if ctx.Command() == "resync <drive>" {
+		if err := bernard.ResyncDrive(db, mg, cli.Resync.Drive, cli.Resync.Scan); err != nil {
+			log.Fatal().
+				Err(err).
+				Str("drive_id", cli.Resync.Drive).
+				Msg("Failed requesting resync")
+		}
+
+		log.Info().
+			Str("drive_id", cli.Resync.Drive).
+			Bool("scan", cli.Resync.Scan).
+			Msg("Resync requested, the drive is fully synced on the next run")
+		return
+	}
+
 	// config
 	file, err := os.Open(cli.Config)
 	if err != nil {
@@ -188,14 +246,6 @@ func main() {
 			Msg("Failed decoding config")
 	}
 
-	// migrator
-	mg, err := migrate.New(db, "migrations")
-	if err != nil {
-		log.Fatal().
-			Err(err).
-			Msg("Failed initialising migrator")
-	}
-
 	// processor
 	proc, err := processor.New(processor.Config{
 		Anchors:    c.Anchors,
@@ -222,7 +272,7 @@ func main() {
 
 	// daemon triggers
 	for _, t := range c.Triggers.Bernard {
-		trigger, err := bernard.New(t, db)
+		trigger, err := bernard.New(t, db, mg)
 		if err != nil {
 			log.Fatal().
 				Err(err).
@@ -245,6 +295,90 @@ func main() {
 		go trigger(proc.Add)
 	}
 
+	for _, t := range c.Triggers.Fanotify {
+		trigger, err := fanotify.New(t)
+		if err != nil {
+			log.Fatal().
+				Err(err).
+				Str("trigger", "fanotify").
+				Msg("Failed initialising trigger")
+		}
+
+		go trigger(proc.Add)
+	}
+
+	for _, t := range c.Triggers.Poll {
+		trigger, err := poll.New(t, db, mg)
+		if err != nil {
+			log.Fatal().
+				Err(err).
+				Str("trigger", "poll").
+				Msg("Failed initialising trigger")
+		}
+
+		go trigger(proc.Add)
+	}
+
+	for _, t := range c.Triggers.Rclone {
+		trigger, err := rclone.New(t, db, mg)
+		if err != nil {
+			log.Fatal().
+				Err(err).
+				Str("trigger", "rclone").
+				Msg("Failed initialising trigger")
+		}
+
+		go trigger(proc.Add)
+	}
+
+	for _, t := range c.Triggers.History {
+		trigger, err := history.New(t, db, mg, proc.Received)
+		if err != nil {
+			log.Fatal().
+				Err(err).
+				Str("trigger", "history").
+				Msg("Failed initialising trigger")
+		}
+
+		go trigger(proc.Add)
+	}
+
+	for _, t := range c.Triggers.MQTT {
+		trigger, err := mqtt.New(t)
+		if err != nil {
+			log.Fatal().
+				Err(err).
+				Str("trigger", "mqtt").
+				Msg("Failed initialising trigger")
+		}
+
+		go trigger(proc.Add)
+	}
+
+	for _, t := range c.Triggers.NATS {
+		trigger, err := nats.New(t)
+		if err != nil {
+			log.Fatal().
+				Err(err).
+				Str("trigger", "nats").
+				Msg("Failed initialising trigger")
+		}
+
+		go trigger(proc.Add)
+	}
+
+	for _, t := range c.Triggers.Spool {
+		trigger, err := spool.New(t)
+		if err != nil {
+			log.Fatal().
+				Err(err).
+				Str("trigger", "spool").
+				Msg("Failed initialising trigger")
+		}
+
+		go trigger(proc.Add)
+	}
+
 	// http triggers
 	router := getRouter(c, proc)
 
@@ -267,12 +401,26 @@ func main() {
 
 	log.Info().
 		Int("manual", 1).
+		Int("bazarr", len(c.Triggers.Bazarr)).
 		Int("bernard", len(c.Triggers.Bernard)).
+		Int("emby", len(c.Triggers.Emby)).
+		Int("fanotify", len(c.Triggers.Fanotify)).
+		Int("history", len(c.Triggers.History)).
 		Int("inotify", len(c.Triggers.Inotify)).
+		Int("jellyfin", len(c.Triggers.Jellyfin)).
 		Int("lidarr", len(c.Triggers.Lidarr)).
+		Int("mqtt", len(c.Triggers.MQTT)).
+		Int("nats", len(c.Triggers.NATS)).
+		Int("plex", len(c.Triggers.Plex)).
+		Int("poll", len(c.Triggers.Poll)).
 		Int("radarr", len(c.Triggers.Radarr)).
+		Int("rclone", len(c.Triggers.Rclone)).
 		Int("readarr", len(c.Triggers.Readarr)).
+		Int("s3", len(c.Triggers.S3)).
+		Int("schedule", len(c.Triggers.Schedule)).
 		Int("sonarr", len(c.Triggers.Sonarr)).
+		Int("spool", len(c.Triggers.Spool)).
+		Int("webhook", len(c.Triggers.Webhook)).
 		Msg("Initialised triggers")
 
 	// targets
@@ -337,6 +485,19 @@ func main() {
 		Int("jellyfin", len(c.Targets.Jellyfin)).
 		Msg("Initialised targets")
 
+	// scheduled triggers depend on the libraries of the targets
+	for _, t := range c.Triggers.Schedule {
+		trigger, err := schedule.New(t, targets, db, mg)
+		if err != nil {
+			log.Fatal().
+				Err(err).
+				Str("trigger", "schedule").
+				Msg("Failed initialising trigger")
+		}
+
+		go trigger(proc.Add)
+	}
+
 	// scan stats
 	if c.ScanStats.Seconds() > 0 {
 		go scanStats(proc, c.ScanStats)
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
@@ -13,11 +15,17 @@ import (
 
 	"github.com/cloudbox/autoscan/processor"
 	"github.com/cloudbox/autoscan/triggers/a_train"
+	"github.com/cloudbox/autoscan/triggers/bazarr"
+	"github.com/cloudbox/autoscan/triggers/bernard"
+	jellyfinhook "github.com/cloudbox/autoscan/triggers/jellyfin"
 	"github.com/cloudbox/autoscan/triggers/lidarr"
 	"github.com/cloudbox/autoscan/triggers/manual"
+	plexhook "github.com/cloudbox/autoscan/triggers/plex"
 	"github.com/cloudbox/autoscan/triggers/radarr"
 	"github.com/cloudbox/autoscan/triggers/readarr"
+	"github.com/cloudbox/autoscan/triggers/s3"
 	"github.com/cloudbox/autoscan/triggers/sonarr"
+	"github.com/cloudbox/autoscan/triggers/webhook"
 )
 
 func pattern(name string) string {
@@ -50,6 +58,17 @@ func getRouter(c config, proc *processor.Processor) chi.Router {
 
 	// Health check
 	r.Get("/health", healthHandler)
+	r.Get("/metrics", metricsHandler(proc))
+
+	// Bernard drive management, only available with authentication as a resync discards the state of a drive
+	if c.Auth.Username != "" && c.Auth.Password != "" {
+		r.Route("/bernard", func(r chi.Router) {
+			r.Use(middleware.BasicAuth("Autoscan 1.x", createCredentials(c)))
+
+			r.Post("/{drive}/resume", resumeDriveHandler)
+			r.Post("/{drive}/resync", resyncDriveHandler)
+		})
+	}
 
 	// HTTP-Triggers
 	r.Route("/triggers", func(r chi.Router) {
@@ -79,6 +98,33 @@ func getRouter(c config, proc *processor.Processor) chi.Router {
 		})
 
 		// OLD-style HTTP-triggers. Can be converted to the /{trigger}/{id} format in a 2.0 release.
+		for _, t := range c.Triggers.Bazarr {
+			trigger, err := bazarr.New(t)
+			if err != nil {
+				log.Fatal().Err(err).Str("trigger", t.Name).Msg("Failed initialising trigger")
+			}
+
+			r.Post(pattern(t.Name), trigger(proc.Add).ServeHTTP)
+		}
+
+		for _, t := range c.Triggers.Emby {
+			trigger, err := jellyfinhook.New(t)
+			if err != nil {
+				log.Fatal().Err(err).Str("trigger", t.Name).Msg("Failed initialising trigger")
+			}
+
+			r.Post(pattern(t.Name), trigger(proc.Add).ServeHTTP)
+		}
+
+		for _, t := range c.Triggers.Jellyfin {
+			trigger, err := jellyfinhook.New(t)
+			if err != nil {
+				log.Fatal().Err(err).Str("trigger", t.Name).Msg("Failed initialising trigger")
+			}
+
+			r.Post(pattern(t.Name), trigger(proc.Add).ServeHTTP)
+		}
+
 		for _, t := range c.Triggers.Lidarr {
 			trigger, err := lidarr.New(t)
 			if err != nil {
@@ -88,6 +134,15 @@ func getRouter(c config, proc *processor.Processor) chi.Router {
 			r.Post(pattern(t.Name), trigger(proc.Add).ServeHTTP)
 		}
 
+		for _, t := range c.Triggers.Plex {
+			trigger, err := plexhook.New(t)
+			if err != nil {
+				log.Fatal().Err(err).Str("trigger", t.Name).Msg("Failed initialising trigger")
+			}
+
+			r.Post(pattern(t.Name), trigger(proc.Add).ServeHTTP)
+		}
+
 		for _, t := range c.Triggers.Radarr {
 			trigger, err := radarr.New(t)
 			if err != nil {
@@ -106,6 +161,15 @@ func getRouter(c config, proc *processor.Processor) chi.Router {
 			r.Post(pattern(t.Name), trigger(proc.Add).ServeHTTP)
 		}
 
+		for _, t := range c.Triggers.S3 {
+			trigger, err := s3.New(t)
+			if err != nil {
+				log.Fatal().Err(err).Str("trigger", t.Name).Msg("Failed initialising trigger")
+			}
+
+			r.Post(pattern(t.Name), trigger(proc.Add).ServeHTTP)
+		}
+
 		for _, t := range c.Triggers.Sonarr {
 			trigger, err := sonarr.New(t)
 			if err != nil {
@@ -114,6 +178,15 @@ func getRouter(c config, proc *processor.Processor) chi.Router {
 
 			r.Post(pattern(t.Name), trigger(proc.Add).ServeHTTP)
 		}
+
+		for _, t := range c.Triggers.Webhook {
+			trigger, err := webhook.New(t)
+			if err != nil {
+				log.Fatal().Err(err).Str("trigger", t.Name).Msg("Failed initialising trigger")
+			}
+
+			r.Post(pattern(t.Name), trigger(proc.Add).ServeHTTP)
+		}
 	})
 
 	return r
@@ -121,5 +194,53 @@ func getRouter(c config, proc *processor.Processor) chi.Router {
 
 // Other Handlers
 func healthHandler(rw http.ResponseWriter, r *http.Request) {
+	type Response struct {
+		Bernard []bernard.DriveHealth `json:"bernard"`
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(rw).Encode(Response{
+		Bernard: bernard.Health(),
+	})
+}
+
+func resumeDriveHandler(rw http.ResponseWriter, r *http.Request) {
+	driveID := chi.URLParam(r, "drive")
+	l := hlog.FromRequest(r).With().Str("drive_id", driveID).Logger()
+
+	err := bernard.Resume(driveID)
+	switch {
+	case errors.Is(err, bernard.ErrDriveNotFound):
+		l.Warn().Msg("Drive not found")
+		rw.WriteHeader(http.StatusNotFound)
+		return
+	case err != nil:
+		l.Error().Err(err).Msg("Failed resuming drive")
+		rw.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	rw.WriteHeader(http.StatusOK)
+}
+
+func resyncDriveHandler(rw http.ResponseWriter, r *http.Request) {
+	driveID := chi.URLParam(r, "drive")
+	scan := r.URL.Query().Get("scan") == "true"
+	l := hlog.FromRequest(r).With().Str("drive_id", driveID).Bool("scan", scan).Logger()
+
+	err := bernard.Resync(driveID, scan)
+	switch {
+	case errors.Is(err, bernard.ErrDriveNotFound):
+		l.Warn().Msg("Drive not found")
+		rw.WriteHeader(http.StatusNotFound)
+		return
+	case err != nil:
+		l.Error().Err(err).Msg("Failed requesting resync")
+		rw.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	l.Info().Msg("Resync requested")
 	rw.WriteHeader(http.StatusOK)
 }
@@ -2,12 +2,16 @@ package main
 
 import (
 	"errors"
+	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/rs/zerolog/log"
 
 	"github.com/cloudbox/autoscan"
 	"github.com/cloudbox/autoscan/processor"
+	"github.com/cloudbox/autoscan/triggers/bernard"
+	"github.com/cloudbox/autoscan/triggers/inotify"
 )
 
 func scanStats(proc *processor.Processor, interval time.Duration) {
@@ -38,3 +42,63 @@ func scanStats(proc *processor.Processor, interval time.Duration) {
 		}
 	}
 }
+
+// metricsHandler exposes the scan stats and the health of the Bernard drives in the Prometheus text format.
+func metricsHandler(proc *processor.Processor) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		remaining, err := proc.ScansRemaining()
+		if err != nil {
+			log.Error().
+				Err(err).
+				Msg("Failed determining amount of remaining scans")
+			rw.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		rw.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+		fmt.Fprintln(rw, "# TYPE autoscan_scans_remaining gauge")
+		fmt.Fprintf(rw, "autoscan_scans_remaining %d\n", remaining)
+		fmt.Fprintln(rw, "# TYPE autoscan_scans_processed_total counter")
+		fmt.Fprintf(rw, "autoscan_scans_processed_total %d\n", proc.ScansProcessed())
+
+		if watches, ok := inotify.Stats(); ok {
+			fmt.Fprintln(rw, "# TYPE autoscan_inotify_directories_watched gauge")
+			fmt.Fprintf(rw, "autoscan_inotify_directories_watched %d\n", watches.Watched)
+			fmt.Fprintln(rw, "# TYPE autoscan_inotify_directories_polled gauge")
+			fmt.Fprintf(rw, "autoscan_inotify_directories_polled %d\n", watches.Polled)
+			fmt.Fprintln(rw, "# TYPE autoscan_inotify_max_user_watches gauge")
+			fmt.Fprintf(rw, "autoscan_inotify_max_user_watches %d\n", watches.Limit)
+		}
+
+		drives := bernard.Health()
+		if len(drives) == 0 {
+			return
+		}
+
+		fmt.Fprintln(rw, "# TYPE autoscan_bernard_drive_consecutive_failures gauge")
+		for _, d := range drives {
+			fmt.Fprintf(rw, "autoscan_bernard_drive_consecutive_failures{drive_id=%q} %d\n", d.ID, d.ConsecutiveFailures)
+		}
+
+		fmt.Fprintln(rw, "# TYPE autoscan_bernard_drive_last_success_timestamp_seconds gauge")
+		for _, d := range drives {
+			lastSuccess := int64(0)
+			if !d.LastSuccess.IsZero() {
+				lastSuccess = d.LastSuccess.Unix()
+			}
+
+			fmt.Fprintf(rw, "autoscan_bernard_drive_last_success_timestamp_seconds{drive_id=%q} %d\n", d.ID, lastSuccess)
+		}
+
+		fmt.Fprintln(rw, "# TYPE autoscan_bernard_drive_stopped gauge")
+		for _, d := range drives {
+			stopped := 0
+			if d.Stopped {
+				stopped = 1
+			}
+
+			fmt.Fprintf(rw, "autoscan_bernard_drive_stopped{drive_id=%q} %d\n", d.ID, stopped)
+		}
+	}
+}
@@ -33,18 +33,33 @@ func newDatastore(db *sql.DB, mg *migrate.Migrator) (*datastore, error) {
 }
 
 const sqlUpsert = `
-INSERT INTO scan (folder, priority, time)
-VALUES (?, ?, ?)
+INSERT INTO scan (folder, priority, time, origin)
+VALUES (?, ?, ?, ?)
 ON CONFLICT (folder) DO UPDATE SET
 	priority = MAX(excluded.priority, scan.priority),
-	time = excluded.time
+	time = excluded.time,
+	origin = CASE WHEN excluded.origin = scan.origin THEN scan.origin ELSE '' END
 `
 
 func (store *datastore) upsert(tx *sql.Tx, scan autoscan.Scan) error {
-	_, err := tx.Exec(sqlUpsert, scan.Folder, scan.Priority, scan.Time)
+	_, err := tx.Exec(sqlUpsert, scan.Folder, scan.Priority, scan.Time, scan.Origin)
+	return err
+}
+
+const sqlUpsertReceived = `
+INSERT INTO received (folder, time)
+VALUES (?, ?)
+ON CONFLICT (folder) DO UPDATE SET
+	time = excluded.time
+`
+
+func (store *datastore) upsertReceived(tx *sql.Tx, scan autoscan.Scan) error {
+	_, err := tx.Exec(sqlUpsertReceived, scan.Folder, now())
 	return err
 }
 
+const sqlDeleteReceived = `DELETE FROM received WHERE time < ?`
+
 func (store *datastore) Upsert(scans []autoscan.Scan) error {
 	tx, err := store.Begin()
 	if err != nil {
@@ -59,11 +74,47 @@ func (store *datastore) Upsert(scans []autoscan.Scan) error {
 
 			return err
 		}
+
+		if err = store.upsertReceived(tx, scan); err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				panic(rollbackErr)
+			}
+
+			return err
+		}
+	}
+
+	// forget folders received a long time ago
+	if _, err = tx.Exec(sqlDeleteReceived, now().Add(-receivedRetention)); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			panic(rollbackErr)
+		}
+
+		return err
 	}
 
 	return tx.Commit()
 }
 
+const sqlGetReceived = `SELECT time FROM received WHERE folder = ?`
+
+// GetReceived returns the time a scan of the folder was last received,
+// or the zero time when the folder was not received within the retention period.
+func (store *datastore) GetReceived(folder string) (time.Time, error) {
+	row := store.QueryRow(sqlGetReceived, folder)
+
+	var received time.Time
+	err := row.Scan(&received)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return received, nil
+	case err != nil:
+		return received, fmt.Errorf("get received: %v: %w", err, autoscan.ErrFatal)
+	}
+
+	return received, nil
+}
+
 const sqlGetScansRemaining = `SELECT COUNT(folder) FROM scan`
 
 func (store *datastore) GetScansRemaining() (int, error) {
@@ -82,7 +133,7 @@ func (store *datastore) GetScansRemaining() (int, error) {
 }
 
 const sqlGetAvailableScan = `
-SELECT folder, priority, time FROM scan
+SELECT folder, priority, time, origin FROM scan
 WHERE time < ?
 ORDER BY priority DESC, time ASC
 LIMIT 1
@@ -92,7 +143,7 @@ func (store *datastore) GetAvailableScan(minAge time.Duration) (autoscan.Scan, e
 	row := store.QueryRow(sqlGetAvailableScan, now().Add(-1*minAge))
 
 	scan := autoscan.Scan{}
-	err := row.Scan(&scan.Folder, &scan.Priority, &scan.Time)
+	err := row.Scan(&scan.Folder, &scan.Priority, &scan.Time, &scan.Origin)
 	switch {
 	case errors.Is(err, sql.ErrNoRows):
 		return scan, autoscan.ErrNoScans
@@ -104,7 +155,7 @@ func (store *datastore) GetAvailableScan(minAge time.Duration) (autoscan.Scan, e
 }
 
 const sqlGetAll = `
-SELECT folder, priority, time FROM scan
+SELECT folder, priority, time, origin FROM scan
 `
 
 func (store *datastore) GetAll() (scans []autoscan.Scan, err error) {
@@ -116,7 +167,7 @@ func (store *datastore) GetAll() (scans []autoscan.Scan, err error) {
 	defer rows.Close()
 	for rows.Next() {
 		scan := autoscan.Scan{}
-		err = rows.Scan(&scan.Folder, &scan.Priority, &scan.Time)
+		err = rows.Scan(&scan.Folder, &scan.Priority, &scan.Time, &scan.Origin)
 		if err != nil {
 			return scans, err
 		}
@@ -140,4 +191,7 @@ func (store *datastore) Delete(scan autoscan.Scan) error {
 	return nil
 }
 
+// how long the folders of received scans are remembered
+const receivedRetention = 7 * 24 * time.Hour
+
 var now = time.Now
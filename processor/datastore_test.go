@@ -15,7 +15,7 @@ import (
 )
 
 const sqlGetScan = `
-SELECT folder, priority, time FROM scan
+SELECT folder, priority, time, origin FROM scan
 WHERE folder = ?
 `
 
@@ -23,7 +23,7 @@ func (store *datastore) GetScan(folder string) (autoscan.Scan, error) {
 	row := store.QueryRow(sqlGetScan, folder)
 
 	scan := autoscan.Scan{}
-	err := row.Scan(&scan.Folder, &scan.Priority, &scan.Time)
+	err := row.Scan(&scan.Folder, &scan.Priority, &scan.Time, &scan.Origin)
 
 	return scan, err
 }
@@ -91,6 +91,38 @@ func TestUpsert(t *testing.T) {
 				Time:     time.Time{}.Add(3),
 			},
 		},
+		{
+			Name: "Origin is kept when the scans share their origin",
+			Scans: []autoscan.Scan{
+				{
+					Time:   time.Time{}.Add(1),
+					Origin: "plex",
+				},
+				{
+					Time:   time.Time{}.Add(2),
+					Origin: "plex",
+				},
+			},
+			WantScan: autoscan.Scan{
+				Time:   time.Time{}.Add(2),
+				Origin: "plex",
+			},
+		},
+		{
+			Name: "Origin is cleared when the scans differ in origin",
+			Scans: []autoscan.Scan{
+				{
+					Time:   time.Time{}.Add(1),
+					Origin: "plex",
+				},
+				{
+					Time: time.Time{}.Add(2),
+				},
+			},
+			WantScan: autoscan.Scan{
+				Time: time.Time{}.Add(2),
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -242,3 +274,48 @@ func TestDelete(t *testing.T) {
 		})
 	}
 }
+
+func TestGetReceived(t *testing.T) {
+	store := getDatastore(t)
+
+	received := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time {
+		return received
+	}
+	defer func() {
+		now = time.Now
+	}()
+
+	err := store.Upsert([]autoscan.Scan{{Folder: "1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := store.GetReceived("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !got.Equal(received) {
+		t.Errorf("Received time does not match: %v", got)
+	}
+
+	// the folders of old scans are forgotten
+	now = func() time.Time {
+		return received.Add(receivedRetention + time.Second)
+	}
+
+	err = store.Upsert([]autoscan.Scan{{Folder: "2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err = store.GetReceived("1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !got.IsZero() {
+		t.Errorf("Expected folder to be forgotten, got: %v", got)
+	}
+}
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS received (
+    "folder" TEXT NOT NULL,
+    "time" DATETIME NOT NULL,
+    PRIMARY KEY(folder)
+)
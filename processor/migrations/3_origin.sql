@@ -0,0 +1,2 @@
+-- the media server the scan originates from, empty when the scan is sent to all targets
+ALTER TABLE scan ADD COLUMN "origin" TEXT NOT NULL DEFAULT ''
@@ -46,6 +46,16 @@ func (p *Processor) Add(scans ...autoscan.Scan) error {
 	return p.store.Upsert(scans)
 }
 
+// Received returns whether a scan of the folder was received since the given time.
+func (p *Processor) Received(folder string, since time.Time) (bool, error) {
+	received, err := p.store.GetReceived(folder)
+	if err != nil {
+		return false, err
+	}
+
+	return !received.IsZero() && !received.Before(since), nil
+}
+
 // ScansRemaining returns the amount of scans remaining
 func (p *Processor) ScansRemaining() (int, error) {
 	return p.store.GetScansRemaining()
@@ -75,6 +85,11 @@ func (p *Processor) callTargets(targets []autoscan.Target, scan autoscan.Scan) e
 	g := new(errgroup.Group)
 
 	for _, target := range targets {
+		// skip the media server the scan originates from
+		if t, ok := target.(autoscan.IdentifiedTarget); ok && scan.Origin != "" && t.ID() == scan.Origin {
+			continue
+		}
+
 		target := target
 		g.Go(func() error {
 			return target.Scan(scan)
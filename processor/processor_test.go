@@ -0,0 +1,87 @@
+package processor
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/cloudbox/autoscan"
+)
+
+type testTarget struct {
+	name    string
+	scanned *[]string
+	lock    *sync.Mutex
+}
+
+func (t testTarget) Scan(autoscan.Scan) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	*t.scanned = append(*t.scanned, t.name)
+	return nil
+}
+
+func (t testTarget) Available() error {
+	return nil
+}
+
+type testIdentifiedTarget struct {
+	testTarget
+}
+
+func (t testIdentifiedTarget) ID() string {
+	return t.name
+}
+
+func TestCallTargets(t *testing.T) {
+	type Test struct {
+		Name     string
+		Scan     autoscan.Scan
+		Expected []string
+	}
+
+	var testCases = []Test{
+		{
+			Name:     "Scan without origin",
+			Scan:     autoscan.Scan{Folder: "/TV/Show"},
+			Expected: []string{"emby", "jellyfin", "plex"},
+		},
+		{
+			Name:     "Skips the target of the origin",
+			Scan:     autoscan.Scan{Folder: "/TV/Show", Origin: "plex"},
+			Expected: []string{"emby", "jellyfin"},
+		},
+		{
+			Name:     "Unknown origin",
+			Scan:     autoscan.Scan{Folder: "/TV/Show", Origin: "other"},
+			Expected: []string{"emby", "jellyfin", "plex"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var scanned []string
+			lock := &sync.Mutex{}
+
+			targets := []autoscan.Target{
+				testTarget{name: "emby", scanned: &scanned, lock: lock},
+				testTarget{name: "jellyfin", scanned: &scanned, lock: lock},
+				testIdentifiedTarget{testTarget{name: "plex", scanned: &scanned, lock: lock}},
+			}
+
+			p := &Processor{}
+			if err := p.callTargets(targets, tc.Scan); err != nil {
+				t.Fatal(err)
+			}
+
+			sort.Strings(scanned)
+			if !reflect.DeepEqual(scanned, tc.Expected) {
+				t.Logf("want: %v", tc.Expected)
+				t.Logf("got:  %v", scanned)
+				t.Errorf("Scanned targets do not equal")
+			}
+		})
+	}
+}
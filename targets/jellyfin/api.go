@@ -76,6 +76,36 @@ func (c apiClient) Available() error {
 	return nil
 }
 
+// ServerID returns the ID of the server, which webhooks include to identify the server.
+func (c apiClient) ServerID() (string, error) {
+	// create request
+	reqURL := autoscan.JoinURL(c.baseURL, "System", "Info")
+	req, err := http.NewRequest("GET", reqURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed creating system info request: %v: %w", err, autoscan.ErrFatal)
+	}
+
+	// send request
+	res, err := c.do(req)
+	if err != nil {
+		return "", fmt.Errorf("system info: %w", err)
+	}
+
+	defer res.Body.Close()
+
+	// decode response
+	type Response struct {
+		ID string `json:"Id"`
+	}
+
+	resp := new(Response)
+	if err := json.NewDecoder(res.Body).Decode(resp); err != nil {
+		return "", fmt.Errorf("failed decoding system info response: %v: %w", err, autoscan.ErrFatal)
+	}
+
+	return resp.ID, nil
+}
+
 type library struct {
 	Name string
 	Path string
@@ -19,6 +19,7 @@ type Config struct {
 type target struct {
 	url       string
 	token     string
+	id        string
 	libraries []library
 
 	log     zerolog.Logger
@@ -39,6 +40,11 @@ func New(c Config) (autoscan.Target, error) {
 
 	api := newAPIClient(c.URL, c.Token, l)
 
+	id, err := api.ServerID()
+	if err != nil {
+		return nil, err
+	}
+
 	libraries, err := api.Libraries()
 	if err != nil {
 		return nil, err
@@ -51,6 +57,7 @@ func New(c Config) (autoscan.Target, error) {
 	return &target{
 		url:       c.URL,
 		token:     c.Token,
+		id:        id,
 		libraries: libraries,
 
 		log:     l,
@@ -63,6 +70,21 @@ func (t target) Available() error {
 	return t.api.Available()
 }
 
+// ID returns the ID of the Jellyfin server,
+// which its webhooks include as the server ID.
+func (t target) ID() string {
+	return t.id
+}
+
+func (t target) Libraries() []string {
+	paths := make([]string, 0, len(t.libraries))
+	for _, l := range t.libraries {
+		paths = append(paths, l.Path)
+	}
+
+	return paths
+}
+
 func (t target) Scan(scan autoscan.Scan) error {
 	// determine library for this scan
 	scanFolder := t.rewrite(scan.Folder)
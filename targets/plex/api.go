@@ -59,32 +59,35 @@ func (c apiClient) do(req *http.Request) (*http.Response, error) {
 	}
 }
 
-func (c apiClient) Version() (string, error) {
+type server struct {
+	Version           string
+	MachineIdentifier string
+}
+
+func (c apiClient) Server() (server, error) {
 	reqURL := autoscan.JoinURL(c.baseURL)
 	req, err := http.NewRequest("GET", reqURL, nil)
 	if err != nil {
-		return "", fmt.Errorf("failed creating version request: %v: %w", err, autoscan.ErrFatal)
+		return server{}, fmt.Errorf("failed creating version request: %v: %w", err, autoscan.ErrFatal)
 	}
 
 	res, err := c.do(req)
 	if err != nil {
-		return "", fmt.Errorf("version: %w", err)
+		return server{}, fmt.Errorf("version: %w", err)
 	}
 
 	defer res.Body.Close()
 
 	type Response struct {
-		MediaContainer struct {
-			Version string
-		}
+		MediaContainer server
 	}
 
 	resp := new(Response)
 	if err := json.NewDecoder(res.Body).Decode(resp); err != nil {
-		return "", fmt.Errorf("failed decoding version response: %v: %w", err, autoscan.ErrFatal)
+		return server{}, fmt.Errorf("failed decoding version response: %v: %w", err, autoscan.ErrFatal)
 	}
 
-	return resp.MediaContainer.Version, nil
+	return resp.MediaContainer, nil
 }
 
 type library struct {
@@ -20,6 +20,7 @@ type Config struct {
 type target struct {
 	url       string
 	token     string
+	id        string
 	libraries []library
 
 	log     zerolog.Logger
@@ -39,14 +40,14 @@ func New(c Config) (autoscan.Target, error) {
 
 	api := newAPIClient(c.URL, c.Token, l)
 
-	version, err := api.Version()
+	srv, err := api.Server()
 	if err != nil {
 		return nil, err
 	}
 
-	l.Debug().Msgf("Plex version: %s", version)
-	if !isSupportedVersion(version) {
-		return nil, fmt.Errorf("plex running unsupported version %s: %w", version, autoscan.ErrFatal)
+	l.Debug().Msgf("Plex version: %s", srv.Version)
+	if !isSupportedVersion(srv.Version) {
+		return nil, fmt.Errorf("plex running unsupported version %s: %w", srv.Version, autoscan.ErrFatal)
 	}
 
 	libraries, err := api.Libraries()
@@ -61,6 +62,7 @@ func New(c Config) (autoscan.Target, error) {
 	return &target{
 		url:       c.URL,
 		token:     c.Token,
+		id:        srv.MachineIdentifier,
 		libraries: libraries,
 
 		log:     l,
@@ -70,10 +72,25 @@ func New(c Config) (autoscan.Target, error) {
 }
 
 func (t target) Available() error {
-	_, err := t.api.Version()
+	_, err := t.api.Server()
 	return err
 }
 
+// ID returns the machine identifier of the Plex server,
+// which Plex webhooks include as the UUID of the server.
+func (t target) ID() string {
+	return t.id
+}
+
+func (t target) Libraries() []string {
+	paths := make([]string, 0, len(t.libraries))
+	for _, l := range t.libraries {
+		paths = append(paths, l.Path)
+	}
+
+	return paths
+}
+
 func (t target) Scan(scan autoscan.Scan) error {
 	// determine library for this scan
 	scanFolder := t.rewrite(scan.Folder)
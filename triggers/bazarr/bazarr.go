@@ -0,0 +1,121 @@
+package bazarr
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/rs/zerolog/hlog"
+
+	"github.com/cloudbox/autoscan"
+)
+
+type Config struct {
+	Name      string             `yaml:"name"`
+	Priority  int                `yaml:"priority"`
+	Rewrite   []autoscan.Rewrite `yaml:"rewrite"`
+	Verbosity string             `yaml:"verbosity"`
+}
+
+// New creates an autoscan-compatible HTTP Trigger for Bazarr custom post-processing requests.
+func New(c Config) (autoscan.HTTPTrigger, error) {
+	rewriter, err := autoscan.NewRewriter(c.Rewrite)
+	if err != nil {
+		return nil, err
+	}
+
+	trigger := func(callback autoscan.ProcessorFunc) http.Handler {
+		return handler{
+			callback: callback,
+			priority: c.Priority,
+			rewrite:  rewriter,
+		}
+	}
+
+	return trigger, nil
+}
+
+type handler struct {
+	priority int
+	rewrite  autoscan.Rewriter
+	callback autoscan.ProcessorFunc
+}
+
+// bazarrEvent holds the post-processing variables of Bazarr.
+// The episode field contains the path of the episode or movie file.
+type bazarrEvent struct {
+	Directory string `json:"directory"`
+	Episode   string `json:"episode"`
+	Subtitles string `json:"subtitles"`
+}
+
+// folder returns the folder in which the subtitles were downloaded.
+func (e bazarrEvent) folder() string {
+	switch {
+	case e.Directory != "":
+		return path.Clean(e.Directory)
+	case e.Episode != "":
+		return path.Dir(e.Episode)
+	case e.Subtitles != "":
+		return path.Dir(e.Subtitles)
+	default:
+		return ""
+	}
+}
+
+func (h handler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	var err error
+	rlog := hlog.FromRequest(r)
+
+	event := new(bazarrEvent)
+
+	// the variables are either given as a JSON body, or as query and form values
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/json" {
+		err = json.NewDecoder(r.Body).Decode(event)
+	} else {
+		err = r.ParseForm()
+		event.Directory = r.Form.Get("directory")
+		event.Episode = r.Form.Get("episode")
+		event.Subtitles = r.Form.Get("subtitles")
+	}
+
+	if err != nil {
+		rlog.Error().Err(err).Msg("Failed decoding request")
+		rw.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	rlog.Trace().Interface("event", event).Msg("Received request")
+
+	folderPath := event.folder()
+	if folderPath == "" {
+		rlog.Error().Msg("Required fields are missing")
+		rw.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	scan := autoscan.Scan{
+		Folder:   h.rewrite(folderPath),
+		Priority: h.priority,
+		Time:     now(),
+	}
+
+	err = h.callback(scan)
+	if err != nil {
+		rlog.Error().Err(err).Msg("Processor could not process scans")
+		rw.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	rlog.Info().
+		Str("path", scan.Folder).
+		Str("subtitles", event.Subtitles).
+		Msg("Scan moved to processor")
+
+	rw.WriteHeader(http.StatusOK)
+}
+
+var now = time.Now
@@ -0,0 +1,174 @@
+package bazarr
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudbox/autoscan"
+)
+
+func TestHandler(t *testing.T) {
+	type Given struct {
+		Config  Config
+		Fixture string
+		Form    url.Values
+	}
+
+	type Expected struct {
+		Scans      []autoscan.Scan
+		StatusCode int
+	}
+
+	type Test struct {
+		Name     string
+		Given    Given
+		Expected Expected
+	}
+
+	standardConfig := Config{
+		Name:     "bazarr",
+		Priority: 5,
+		Rewrite: []autoscan.Rewrite{{
+			From: "^/(Movies|TV)/",
+			To:   "/mnt/unionfs/Media/$1/",
+		}},
+	}
+
+	currentTime := time.Now()
+	now = func() time.Time {
+		return currentTime
+	}
+
+	var testCases = []Test{
+		{
+			"Episode subtitles",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/episode.json",
+			},
+			Expected{
+				StatusCode: 200,
+				Scans: []autoscan.Scan{
+					{
+						Folder:   "/mnt/unionfs/Media/TV/Westworld/Season 1",
+						Priority: 5,
+						Time:     currentTime,
+					},
+				},
+			},
+		},
+		{
+			"Movie subtitles without directory",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/movie.json",
+			},
+			Expected{
+				StatusCode: 200,
+				Scans: []autoscan.Scan{
+					{
+						Folder:   "/mnt/unionfs/Media/Movies/Interstellar (2014)",
+						Priority: 5,
+						Time:     currentTime,
+					},
+				},
+			},
+		},
+		{
+			"Form values",
+			Given{
+				Config: standardConfig,
+				Form: url.Values{
+					"episode": []string{"/Movies/Parasite (2019)/Parasite (2019).mkv"},
+				},
+			},
+			Expected{
+				StatusCode: 200,
+				Scans: []autoscan.Scan{
+					{
+						Folder:   "/mnt/unionfs/Media/Movies/Parasite (2019)",
+						Priority: 5,
+						Time:     currentTime,
+					},
+				},
+			},
+		},
+		{
+			"Returns bad request when the paths are missing",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/missing_fields.json",
+			},
+			Expected{
+				StatusCode: 400,
+			},
+		},
+		{
+			"Returns bad request on invalid JSON",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/invalid.json",
+			},
+			Expected{
+				StatusCode: 400,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			callback := func(scans ...autoscan.Scan) error {
+				if !reflect.DeepEqual(tc.Expected.Scans, scans) {
+					t.Log(scans)
+					t.Log(tc.Expected.Scans)
+					t.Errorf("Scans do not equal")
+					return errors.New("Scans do not equal")
+				}
+
+				return nil
+			}
+
+			trigger, err := New(tc.Given.Config)
+			if err != nil {
+				t.Fatalf("Could not create Bazarr Trigger: %v", err)
+			}
+
+			server := httptest.NewServer(trigger(callback))
+			defer server.Close()
+
+			var body io.Reader
+			contentType := "application/json"
+
+			if tc.Given.Form != nil {
+				body = strings.NewReader(tc.Given.Form.Encode())
+				contentType = "application/x-www-form-urlencoded"
+			} else {
+				request, err := os.Open(tc.Given.Fixture)
+				if err != nil {
+					t.Fatalf("Could not open the fixture: %s", tc.Given.Fixture)
+				}
+
+				defer request.Close()
+				body = request
+			}
+
+			res, err := http.Post(server.URL, contentType, body)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+
+			defer res.Body.Close()
+			if res.StatusCode != tc.Expected.StatusCode {
+				t.Errorf("Status codes do not match: %d vs %d", res.StatusCode, tc.Expected.StatusCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package bernard
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/m-rots/stubbs"
+)
+
+const (
+	driveScope = "https://www.googleapis.com/auth/drive.readonly"
+	tokenURL   = "https://oauth2.googleapis.com/token"
+
+	// lifetime of the access tokens of an impersonated user, in seconds
+	tokenLifetime = 3600
+)
+
+type authenticator interface {
+	AccessToken() (string, int64, error)
+	Email() string
+}
+
+// newAuthenticator creates the authenticator of a service account.
+// With a subject, the service account impersonates that user through domain-wide delegation.
+func newAuthenticator(path string, subject string) (authenticator, error) {
+	if subject == "" {
+		return stubbs.FromFile(path, []string{driveScope})
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	sa := struct {
+		Email string `json:"client_email"`
+		Key   string `json:"private_key"`
+	}{}
+
+	if err := json.NewDecoder(file).Decode(&sa); err != nil {
+		return nil, fmt.Errorf("decoding service account: %w", err)
+	}
+
+	key, err := stubbs.ParseKey(sa.Key)
+	if err != nil {
+		return nil, fmt.Errorf("parsing private key: %w", err)
+	}
+
+	return &delegatedAuth{
+		email:    sa.Email,
+		subject:  subject,
+		key:      key,
+		scopes:   []string{driveScope},
+		tokenURL: tokenURL,
+		client:   &http.Client{Timeout: 15 * time.Second},
+	}, nil
+}
+
+// delegatedAuth creates access tokens of a service account acting on behalf of a user.
+type delegatedAuth struct {
+	email    string
+	subject  string
+	key      *rsa.PrivateKey
+	scopes   []string
+	tokenURL string
+	client   *http.Client
+
+	mtx   sync.Mutex
+	token string
+	exp   int64
+}
+
+func (a *delegatedAuth) Email() string {
+	return a.email
+}
+
+// AccessToken returns the cached access token, or requests a new token once it is about to expire.
+func (a *delegatedAuth) AccessToken() (string, int64, error) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	if a.token != "" && now().Unix() < a.exp {
+		return a.token, a.exp, nil
+	}
+
+	assertion, exp, err := a.assertion()
+	if err != nil {
+		return "", 0, err
+	}
+
+	res, err := a.client.PostForm(a.tokenURL, url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	})
+	if err != nil {
+		return "", 0, fmt.Errorf("requesting access token: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != 200 {
+		return "", 0, fmt.Errorf("requesting access token: %s", res.Status)
+	}
+
+	response := struct {
+		AccessToken string `json:"access_token"`
+	}{}
+
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return "", 0, fmt.Errorf("decoding access token: %w", err)
+	}
+
+	if response.AccessToken == "" {
+		return "", 0, fmt.Errorf("did not retrieve access token")
+	}
+
+	// refresh slightly ahead of the expiry
+	a.token = response.AccessToken
+	a.exp = exp - 10
+
+	return a.token, a.exp, nil
+}
+
+// assertion creates the signed JWT exchanged for an access token of the subject.
+func (a *delegatedAuth) assertion() (string, int64, error) {
+	iat := now().Unix()
+	exp := iat + tokenLifetime
+
+	encode := func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+
+		return base64.RawURLEncoding.EncodeToString(b), nil
+	}
+
+	header, err := encode(map[string]string{
+		"alg": "RS256",
+		"typ": "JWT",
+	})
+	if err != nil {
+		return "", 0, err
+	}
+
+	claims, err := encode(map[string]interface{}{
+		"iss":   a.email,
+		"sub":   a.subject,
+		"scope": strings.Join(a.scopes, " "),
+		"aud":   a.tokenURL,
+		"iat":   iat,
+		"exp":   exp,
+	})
+	if err != nil {
+		return "", 0, err
+	}
+
+	message := header + "." + claims
+	hashed := sha256.Sum256([]byte(message))
+
+	signature, err := rsa.SignPKCS1v15(rand.Reader, a.key, crypto.SHA256, hashed[:])
+	if err != nil {
+		return "", 0, fmt.Errorf("signing assertion: %w", err)
+	}
+
+	return message + "." + base64.RawURLEncoding.EncodeToString(signature), exp, nil
+}
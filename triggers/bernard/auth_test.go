@@ -0,0 +1,94 @@
+package bernard
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDelegatedAuth(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		parts := strings.Split(r.PostFormValue("assertion"), ".")
+		if len(parts) != 3 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		b, err := base64.RawURLEncoding.DecodeString(parts[1])
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		claims := make(map[string]interface{})
+		if err := json.Unmarshal(b, &claims); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if claims["iss"] != "sa@project.iam.gserviceaccount.com" || claims["sub"] != "user@example.com" ||
+			claims["aud"] != "http://"+r.Host {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"access_token": "token"}`))
+	}))
+	defer server.Close()
+
+	current := time.Unix(1600000000, 0)
+	now = func() time.Time {
+		return current
+	}
+	defer func() {
+		now = time.Now
+	}()
+
+	auth := &delegatedAuth{
+		email:    "sa@project.iam.gserviceaccount.com",
+		subject:  "user@example.com",
+		key:      key,
+		scopes:   []string{driveScope},
+		tokenURL: server.URL,
+		client:   http.DefaultClient,
+	}
+
+	for i := 0; i < 2; i++ {
+		token, _, err := auth.AccessToken()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if token != "token" {
+			t.Errorf("Expected token, got: %v", token)
+		}
+	}
+
+	if requests != 1 {
+		t.Errorf("Expected the token to be cached, got %d requests", requests)
+	}
+
+	// token expired
+	current = current.Add(time.Hour)
+	if _, _, err := auth.AccessToken(); err != nil {
+		t.Fatal(err)
+	}
+
+	if requests != 2 {
+		t.Errorf("Expected the token to be refreshed, got %d requests", requests)
+	}
+}
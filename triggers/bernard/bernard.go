@@ -5,27 +5,34 @@ import (
 	"errors"
 	"fmt"
 	"path/filepath"
+	"sync"
 	"time"
 
 	lowe "github.com/l3uddz/bernard"
 	ds "github.com/l3uddz/bernard/datastore"
-	"github.com/l3uddz/bernard/datastore/sqlite"
-	"github.com/m-rots/stubbs"
 	"github.com/robfig/cron/v3"
 	"github.com/rs/zerolog"
 
 	"github.com/cloudbox/autoscan"
+	"github.com/cloudbox/autoscan/migrate"
 )
 
 const (
-	maxSyncRetries = 5
+	// consecutive failures after which a drive is reported as unhealthy
+	unhealthyFailures = 5
+
+	// maximum time between attempts of a failing drive
+	defaultMaxBackoff = time.Hour
 )
 
 type Config struct {
 	AccountPath  string             `yaml:"account"`
+	Subject      string             `yaml:"subject"`
 	CronSchedule string             `yaml:"cron"`
 	Priority     int                `yaml:"priority"`
 	TimeOffset   time.Duration      `yaml:"time-offset"`
+	MaxBackoff   time.Duration      `yaml:"max-backoff"`
+	RateLimit    RateLimit          `yaml:"rate-limit"`
 	Verbosity    string             `yaml:"verbosity"`
 	Rewrite      []autoscan.Rewrite `yaml:"rewrite"`
 	Include      []string           `yaml:"include"`
@@ -37,72 +44,127 @@ type Config struct {
 		Include    []string           `yaml:"include"`
 		Exclude    []string           `yaml:"exclude"`
 	} `yaml:"drives"`
+	MyDrive struct {
+		Enabled    bool               `yaml:"enabled"`
+		TimeOffset time.Duration      `yaml:"time-offset"`
+		Rewrite    []autoscan.Rewrite `yaml:"rewrite"`
+		Include    []string           `yaml:"include"`
+		Exclude    []string           `yaml:"exclude"`
+	} `yaml:"my-drive"`
+	RollUp struct {
+		Depth       int      `yaml:"depth"`
+		Libraries   []string `yaml:"libraries"`
+		FileParents bool     `yaml:"file-parents"`
+	} `yaml:"roll-up"`
+	Discovery struct {
+		Enabled  bool          `yaml:"enabled"`
+		Interval time.Duration `yaml:"interval"`
+		Include  []string      `yaml:"include"`
+		Exclude  []string      `yaml:"exclude"`
+	} `yaml:"discovery"`
 }
 
-func New(c Config, db *sql.DB) (autoscan.Trigger, error) {
+func New(c Config, db *sql.DB, mg *migrate.Migrator) (autoscan.Trigger, error) {
 	l := autoscan.GetLogger(c.Verbosity).With().
 		Str("trigger", "bernard").
 		Logger()
 
-	const scope = "https://www.googleapis.com/auth/drive.readonly"
-	auth, err := stubbs.FromFile(c.AccountPath, []string{scope})
+	auth, err := newAuthenticator(c.AccountPath, c.Subject)
 	if err != nil {
 		return nil, fmt.Errorf("%v: %w", err, autoscan.ErrFatal)
 	}
 
-	store, err := sqlite.FromDB(db)
+	store, err := newDatastore(db, mg)
 	if err != nil {
 		return nil, fmt.Errorf("%v: %w", err, autoscan.ErrFatal)
 	}
 
-	limiter, err := getRateLimiter(auth.Email())
+	limiter, err := getRateLimiter(auth.Email(), c.RateLimit)
 	if err != nil {
 		return nil, fmt.Errorf("%v: %w", err, autoscan.ErrFatal)
 	}
 
+	client := newRateLimitClient(limiter, l)
+
+	const safeSleep = 120 * time.Second
+
 	bernard := lowe.New(auth, store,
+		lowe.WithClient(client),
 		lowe.WithPreRequestHook(limiter.Wait),
-		lowe.WithSafeSleep(120*time.Second))
+		lowe.WithSafeSleep(safeSleep))
 
 	var drives []drive
 	for _, d := range c.Drives {
-		d := d
+		timeOffset := c.TimeOffset
+		if d.TimeOffset.Seconds() > 0 {
+			timeOffset = d.TimeOffset
+		}
 
-		rewriter, err := autoscan.NewRewriter(append(d.Rewrite, c.Rewrite...))
+		drive, err := newDrive(d.ID, timeOffset,
+			append(d.Rewrite, c.Rewrite...), append(d.Include, c.Include...), append(d.Exclude, c.Exclude...))
 		if err != nil {
 			return nil, err
 		}
 
-		filterer, err := autoscan.NewFilterer(append(d.Include, c.Include...), append(d.Exclude, c.Exclude...))
+		drives = append(drives, drive)
+	}
+
+	// the ID of the my drive is resolved once the trigger starts
+	var md *myDrive
+	if c.MyDrive.Enabled {
+		timeOffset := c.TimeOffset
+		if c.MyDrive.TimeOffset.Seconds() > 0 {
+			timeOffset = c.MyDrive.TimeOffset
+		}
+
+		drive, err := newDrive(myDriveID, timeOffset, append(c.MyDrive.Rewrite, c.Rewrite...),
+			append(c.MyDrive.Include, c.Include...), append(c.MyDrive.Exclude, c.Exclude...))
 		if err != nil {
 			return nil, err
 		}
 
-		scanTime := func() time.Time {
-			if d.TimeOffset.Seconds() > 0 {
-				return time.Now().Add(d.TimeOffset)
-			}
-			return time.Now().Add(c.TimeOffset)
+		drive.MyDrive = true
+		md = newMyDrive(auth, client, limiter, store, safeSleep)
+		drives = append(drives, drive)
+	}
+
+	// discovered drives use the trigger-wide configuration
+	var discover *discovery
+	var newDiscoveredDrive func(id string) (drive, error)
+
+	if c.Discovery.Enabled {
+		discover, err = newDiscovery(auth, client, limiter, c.Discovery.Interval, c.Discovery.Include, c.Discovery.Exclude)
+		if err != nil {
+			return nil, fmt.Errorf("discovery: %v: %w", err, autoscan.ErrFatal)
 		}
 
-		drives = append(drives, drive{
-			ID:       d.ID,
-			Rewriter: rewriter,
-			Allowed:  filterer,
-			ScanTime: scanTime,
-		})
+		newDiscoveredDrive = func(id string) (drive, error) {
+			return newDrive(id, c.TimeOffset, c.Rewrite, c.Include, c.Exclude)
+		}
+	}
+
+	maxBackoff := c.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = defaultMaxBackoff
 	}
 
 	trigger := func(callback autoscan.ProcessorFunc) {
-		d := daemon{
+		d := &daemon{
 			log:          l,
 			callback:     callback,
 			cronSchedule: c.CronSchedule,
 			priority:     c.Priority,
 			drives:       drives,
 			bernard:      bernard,
-			store:        &bds{store},
+			myDrive:      md,
+			store:        store,
+			rollUp:       RollUp(c.RollUp),
 			limiter:      limiter,
+			maxBackoff:   maxBackoff,
+			discovery:    discover,
+			newDrive:     newDiscoveredDrive,
+			jobs:         make(map[string]cron.EntryID),
+			discovered:   make(map[string]bool),
 		}
 
 		// start job(s)
@@ -119,197 +181,450 @@ func New(c Config, db *sql.DB) (autoscan.Trigger, error) {
 
 type drive struct {
 	ID       string
+	MyDrive  bool
 	Rewriter autoscan.Rewriter
 	Allowed  autoscan.Filterer
 	ScanTime func() time.Time
 }
 
+func newDrive(id string, timeOffset time.Duration, rewrite []autoscan.Rewrite, includes []string, excludes []string) (drive, error) {
+	rewriter, err := autoscan.NewRewriter(rewrite)
+	if err != nil {
+		return drive{}, err
+	}
+
+	filterer, err := autoscan.NewFilterer(includes, excludes)
+	if err != nil {
+		return drive{}, err
+	}
+
+	return drive{
+		ID:       id,
+		Rewriter: rewriter,
+		Allowed:  filterer,
+		ScanTime: func() time.Time {
+			return time.Now().Add(timeOffset)
+		},
+	}, nil
+}
+
 type daemon struct {
 	callback     autoscan.ProcessorFunc
 	cronSchedule string
 	priority     int
 	drives       []drive
 	bernard      *lowe.Bernard
+	myDrive      *myDrive
 	store        *bds
+	rollUp       RollUp
 	log          zerolog.Logger
 	limiter      *rateLimiter
+	maxBackoff   time.Duration
+
+	discovery  *discovery
+	newDrive   func(id string) (drive, error)
+	cron       *cron.Cron
+	jobs       map[string]cron.EntryID
+	discovered map[string]bool
+	mtx        sync.Mutex
 }
 
 type syncJob struct {
-	log      zerolog.Logger
-	attempts int
-	errors   []error
-
-	cron  *cron.Cron
-	jobID cron.EntryID
-	fn    func() error
+	log        zerolog.Logger
+	maxBackoff time.Duration
+	fn         func() error
+	resync     func(scan bool) error
+
+	mtx         sync.Mutex
+	failures    int
+	lastSuccess time.Time
+	lastError   error
+	nextAttempt time.Time
+	stopped     bool
 }
 
 func (s *syncJob) Run() {
-	// increase attempt counter
-	s.attempts++
+	s.mtx.Lock()
+	if s.stopped || now().Before(s.nextAttempt) {
+		s.mtx.Unlock()
+		return
+	}
+	s.mtx.Unlock()
 
 	// run job
 	err := s.fn()
 
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
 	// handle job response
 	switch {
 	case err == nil:
 		// job completed successfully
-		s.attempts = 0
-		s.errors = s.errors[:0]
+		if s.failures > 0 {
+			s.log.Info().
+				Int("failures", s.failures).
+				Msg("Drive recovered")
+		}
+
+		s.failures = 0
+		s.lastSuccess = now()
+		s.lastError = nil
+		s.nextAttempt = time.Time{}
 		return
 
-	case errors.Is(err, lowe.ErrInvalidCredentials), errors.Is(err, ds.ErrDataAnomaly), errors.Is(err, lowe.ErrNetwork):
-		//retryable error occurred
-		s.log.Trace().
+	case errors.Is(err, lowe.ErrNotFound):
+		// the drive does not exist or cannot be accessed with the configured account, retrying will not help
+		s.log.Error().
 			Err(err).
-			Int("attempts", s.attempts).
-			Msg("Retryable error occurred while syncing drive")
-		s.errors = append(s.errors, err)
+			Msg("Drive cannot be accessed, drive has been stopped until resumed...")
+
+		s.failures++
+		s.lastError = err
+		s.stopped = true
+		return
 
 	case errors.Is(err, autoscan.ErrFatal):
-		// fatal error occurred, we cannot recover from this safely
+		// the processor or datastore failed, the changes are retried by the next attempt
 		s.log.Error().
 			Err(err).
-			Msg("Fatal error occurred while syncing drive, drive has been stopped...")
+			Int("attempts", s.failures+1).
+			Msg("Fatal error occurred while syncing drive")
 
-		s.cron.Remove(s.jobID)
-		return
+	case errors.Is(err, lowe.ErrInvalidCredentials), errors.Is(err, ds.ErrDataAnomaly), errors.Is(err, lowe.ErrNetwork):
+		//retryable error occurred
+		s.log.Trace().
+			Err(err).
+			Int("attempts", s.failures+1).
+			Msg("Retryable error occurred while syncing drive")
 
-	case err != nil:
+	default:
 		// an un-expected/un-handled error occurred, this should be retryable with the same retry logic
 		s.log.Warn().
 			Err(err).
-			Int("attempts", s.attempts).
+			Int("attempts", s.failures+1).
 			Msg("Unexpected error occurred while syncing drive")
-		s.errors = append(s.errors, err)
 	}
 
-	// abort if max retries reached
-	if s.attempts >= maxSyncRetries {
+	s.failures++
+	s.lastError = err
+	s.nextAttempt = now().Add(backoff(s.failures, s.maxBackoff))
+
+	if s.failures >= unhealthyFailures {
 		s.log.Error().
-			Errs("error", s.errors).
-			Int("attempts", s.attempts).
-			Msg("Consecutive errors occurred while syncing drive, drive has been stopped...")
+			Err(err).
+			Int("attempts", s.failures).
+			Time("next_attempt", s.nextAttempt).
+			Msg("Consecutive errors occurred while syncing drive, retrying with backoff...")
+	}
+}
+
+func (s *syncJob) health(driveID string) DriveHealth {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
 
-		s.cron.Remove(s.jobID)
+	h := DriveHealth{
+		ID:                  driveID,
+		LastSuccess:         s.lastSuccess,
+		ConsecutiveFailures: s.failures,
+		NextAttempt:         s.nextAttempt,
+		Stopped:             s.stopped,
 	}
+
+	if s.lastError != nil {
+		h.LastError = s.lastError.Error()
+	}
+
+	return h
+}
+
+func (s *syncJob) resume() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.failures = 0
+	s.nextAttempt = time.Time{}
+	s.stopped = false
+
+	s.log.Info().Msg("Drive resumed")
 }
 
-func newSyncJob(c *cron.Cron, log zerolog.Logger, job func() error) *syncJob {
+// backoff returns the time to wait after consecutive failures,
+// doubling from a minute up to the ceiling.
+func backoff(failures int, ceiling time.Duration) time.Duration {
+	wait := time.Minute
+	for i := 1; i < failures && wait < ceiling; i++ {
+		wait *= 2
+	}
+
+	if wait > ceiling {
+		return ceiling
+	}
+
+	return wait
+}
+
+func newSyncJob(log zerolog.Logger, maxBackoff time.Duration, job func() error) *syncJob {
 	return &syncJob{
-		log:      log,
-		attempts: 0,
-		errors:   make([]error, 0),
-		cron:     c,
-		fn:       job,
+		log:        log,
+		maxBackoff: maxBackoff,
+		fn:         job,
 	}
 }
 
-func (d daemon) startAutoSync() error {
-	c := cron.New()
+func (d *daemon) startAutoSync() error {
+	d.cron = cron.New()
 
 	for _, drive := range d.drives {
-		drive := drive
-		fullSync := false
-		l := d.withDriveLog(drive.ID)
+		if drive.MyDrive {
+			root, err := d.myDrive.Root()
+			if err != nil {
+				return fmt.Errorf("retrieving root of my drive: %w", err)
+			}
 
-		// full sync required?
-		_, err := d.store.PageToken(drive.ID)
-		switch {
-		case errors.Is(err, ds.ErrFullSync):
-			fullSync = true
-		case err != nil:
-			return fmt.Errorf("%v: determining if full sync required: %v: %w",
+			drive.ID = root.ID
+		}
+
+		if err := d.addDrive(drive); err != nil {
+			return err
+		}
+	}
+
+	if d.discovery != nil {
+		d.discover()
+
+		_, err := d.cron.AddFunc(fmt.Sprintf("@every %s", d.discovery.interval), d.discover)
+		if err != nil {
+			return fmt.Errorf("creating discovery job: %w", err)
+		}
+	}
+
+	d.cron.Start()
+	return nil
+}
+
+// discover adds a sync job for every new shared drive matching the filters,
+// and stops the jobs of discovered drives which are no longer accessible.
+// Drives in the configuration are never stopped by discovery.
+func (d *daemon) discover() {
+	drives, err := d.discovery.Drives()
+	if err != nil {
+		d.log.Error().
+			Err(err).
+			Msg("Failed discovering drives")
+		return
+	}
+
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	seen := make(map[string]bool)
+	for _, sd := range drives {
+		if !d.discovery.Allowed(sd) {
+			continue
+		}
+
+		seen[sd.ID] = true
+		if _, ok := d.jobs[sd.ID]; ok {
+			continue
+		}
+
+		l := d.log.With().
+			Str("drive_id", sd.ID).
+			Str("drive_name", sd.Name).
+			Logger()
+
+		drive, err := d.newDrive(sd.ID)
+		if err == nil {
+			err = d.addDrive(drive)
+		}
+
+		if err != nil {
+			l.Error().
+				Err(err).
+				Msg("Failed adding discovered drive")
+			continue
+		}
+
+		d.discovered[sd.ID] = true
+		l.Info().Msg("Discovered drive")
+	}
+
+	for id := range d.discovered {
+		if seen[id] {
+			continue
+		}
+
+		d.cron.Remove(d.jobs[id])
+		delete(d.jobs, id)
+		unregisterJob(id)
+		delete(d.discovered, id)
+
+		l := d.withDriveLog(id)
+		l.Warn().Msg("Drive is no longer accessible, drive has been stopped...")
+	}
+}
+
+// addDrive creates the sync job of a drive.
+// Drives are fully synced when they were not synced before, or when a resync was requested.
+func (d *daemon) addDrive(drive drive) error {
+	l := d.withDriveLog(drive.ID)
+
+	// create job
+	job := newSyncJob(l, d.maxBackoff, func() error {
+		// acquire lock
+		if err := d.limiter.Acquire(1); err != nil {
+			return fmt.Errorf("%v: acquiring sync semaphore: %v: %w",
 				drive.ID, err, autoscan.ErrFatal)
 		}
+		defer d.limiter.Release(1)
 
-		// create job
-		job := newSyncJob(c, l, func() error {
-			// acquire lock
-			if err := d.limiter.Acquire(1); err != nil {
-				return fmt.Errorf("%v: acquiring sync semaphore: %v: %w",
+		// remove the drive from the datastore when a resync was requested
+		resync, err := d.store.ResyncRequest(drive.ID)
+		if err != nil {
+			return fmt.Errorf("%v: retrieving resync request: %v: %w",
+				drive.ID, err, autoscan.ErrFatal)
+		}
+
+		if resync != nil && !resync.Reset {
+			if err := d.store.ResetDrive(drive.ID); err != nil {
+				return fmt.Errorf("%v: resetting drive: %v: %w",
 					drive.ID, err, autoscan.ErrFatal)
 			}
-			defer d.limiter.Release(1)
 
-			// full sync
-			if fullSync {
-				l.Info().Msg("Starting full sync")
-				start := time.Now()
+			l.Info().Msg("Drive reset for a full resync")
+		}
+
+		// full sync required?
+		_, err = d.store.PageToken(drive.ID)
+		fullSync := errors.Is(err, ds.ErrFullSync)
 
-				if err := d.bernard.FullSync(drive.ID); err != nil {
-					return fmt.Errorf("%v: performing full sync: %w", drive.ID, err)
-				}
+		if fullSync {
+			l.Info().Msg("Starting full sync")
+			start := time.Now()
 
-				l.Info().Msgf("Finished full sync in %s", time.Since(start))
-				fullSync = false
-				return nil
+			if err := d.syncer(drive).FullSync(drive.ID); err != nil {
+				return fmt.Errorf("%v: performing full sync: %w", drive.ID, err)
 			}
 
-			// create partial sync
-			dh, diff := d.store.NewDifferencesHook()
-			ph := NewPostProcessBernardDiff(drive.ID, d.store, diff)
-			ch, paths := NewPathsHook(drive.ID, d.store, diff)
+			l.Info().Msgf("Finished full sync in %s", time.Since(start))
+		}
 
-			l.Trace().Msg("Running partial sync")
-			start := time.Now()
+		if resync != nil {
+			return d.completeResync(&drive, resync, l)
+		}
 
-			// do partial sync
-			err := d.bernard.PartialSync(drive.ID, dh, ph, ch)
-			if err != nil {
-				return fmt.Errorf("%v: performing partial sync: %w", drive.ID, err)
-			}
+		if fullSync {
+			return nil
+		}
 
+		// create partial sync
+		dh, diff := d.store.NewDifferencesHook()
+		ph := NewPostProcessBernardDiff(drive.ID, d.store, diff)
+		ch, paths := NewPathsHook(drive.ID, drive.MyDrive, d.store, diff, d.rollUp)
+
+		l.Trace().Msg("Running partial sync")
+		start := time.Now()
+
+		// do partial sync
+		err = d.syncer(drive).PartialSync(drive.ID, dh, ph, ch)
+		if err != nil {
+			return fmt.Errorf("%v: performing partial sync: %w", drive.ID, err)
+		}
+
+		l.Trace().
+			Int("new", len(paths.NewFolders)).
+			Int("old", len(paths.OldFolders)).
+			Msgf("Partial sync finished in %s", time.Since(start))
+
+		// translate paths to scan task
+		task := d.getScanTask(&(drive), paths)
+
+		// move scans to processor
+		if len(task.scans) > 0 {
 			l.Trace().
-				Int("new", len(paths.NewFolders)).
-				Int("old", len(paths.OldFolders)).
-				Msgf("Partial sync finished in %s", time.Since(start))
-
-			// translate paths to scan task
-			task := d.getScanTask(&(drive), paths)
-
-			// move scans to processor
-			if len(task.scans) > 0 {
-				l.Trace().
-					Interface("scans", task.scans).
-					Msg("Scans moving to processor")
-
-				err := d.callback(task.scans...)
-				if err != nil {
-					return fmt.Errorf("%v: moving scans to processor: %v: %w",
-						drive.ID, err, autoscan.ErrFatal)
-				}
-
-				l.Info().
-					Int("added", task.added).
-					Int("removed", task.removed).
-					Msg("Scan moved to processor")
+				Interface("scans", task.scans).
+				Msg("Scans moving to processor")
+
+			err := d.callback(task.scans...)
+			if err != nil {
+				return fmt.Errorf("%v: moving scans to processor: %v: %w",
+					drive.ID, err, autoscan.ErrFatal)
 			}
 
-			return nil
-		})
+			l.Info().
+				Int("added", task.added).
+				Int("removed", task.removed).
+				Msg("Scan moved to processor")
+		}
 
-		id, err := c.AddJob(d.cronSchedule, cron.NewChain(cron.SkipIfStillRunning(cron.DiscardLogger)).Then(job))
-		if err != nil {
-			return fmt.Errorf("%v: creating auto sync job for drive: %w", drive.ID, err)
+		return nil
+	})
+
+	id, err := d.cron.AddJob(d.cronSchedule, cron.NewChain(cron.SkipIfStillRunning(cron.DiscardLogger)).Then(job))
+	if err != nil {
+		return fmt.Errorf("%v: creating auto sync job for drive: %w", drive.ID, err)
+	}
+
+	job.resync = func(scan bool) error {
+		return d.store.RequestResync(drive.ID, scan)
+	}
+
+	d.jobs[drive.ID] = id
+	registerJob(drive.ID, job)
+	return nil
+}
+
+// completeResync queues a scan of the root of the drive when requested along with the resync.
+func (d *daemon) completeResync(drive *drive, resync *resyncRequest, l zerolog.Logger) error {
+	if resync.Scan {
+		root := drive.Rewriter("/")
+
+		if drive.Allowed(root) {
+			err := d.callback(autoscan.Scan{
+				Folder:   filepath.Clean(root),
+				Priority: d.priority,
+				Time:     drive.ScanTime(),
+			})
+			if err != nil {
+				return fmt.Errorf("%v: moving scans to processor: %v: %w",
+					drive.ID, err, autoscan.ErrFatal)
+			}
+
+			l.Info().
+				Str("path", root).
+				Msg("Scan moved to processor")
 		}
+	}
 
-		job.jobID = id
+	if err := d.store.CompleteResync(drive.ID); err != nil {
+		return fmt.Errorf("%v: completing resync: %v: %w",
+			drive.ID, err, autoscan.ErrFatal)
 	}
 
-	c.Start()
+	l.Info().Msg("Finished resync")
 	return nil
 }
 
+// ResyncDrive requests a full sync of a drive on the next run of its trigger,
+// optionally followed by a scan of the root of the drive.
+// The request is stored in the datastore, autoscan does not have to be running.
+func ResyncDrive(db *sql.DB, mg *migrate.Migrator, driveID string, scan bool) error {
+	store, err := newDatastore(db, mg)
+	if err != nil {
+		return err
+	}
+
+	return store.RequestResync(driveID, scan)
+}
+
 type scanTask struct {
 	scans   []autoscan.Scan
 	added   int
 	removed int
 }
 
-func (d daemon) getScanTask(drive *drive, paths *Paths) *scanTask {
+func (d *daemon) getScanTask(drive *drive, paths *Paths) *scanTask {
 	pathMap := make(map[string]int)
 	task := &scanTask{
 		scans:   make([]autoscan.Scan, 0),
@@ -374,7 +689,16 @@ func (d daemon) getScanTask(drive *drive, paths *Paths) *scanTask {
 	return task
 }
 
-func (d daemon) withDriveLog(driveID string) zerolog.Logger {
+// syncer returns the syncer of a drive, the Bernard library only supports shared drives.
+func (d *daemon) syncer(drive drive) syncer {
+	if drive.MyDrive {
+		return d.myDrive
+	}
+
+	return d.bernard
+}
+
+func (d *daemon) withDriveLog(driveID string) zerolog.Logger {
 	drive, err := d.store.GetDrive(driveID)
 	if err != nil {
 		return d.log.With().Str("drive_id", driveID).Logger()
@@ -382,3 +706,5 @@ func (d daemon) withDriveLog(driveID string) zerolog.Logger {
 
 	return d.log.With().Str("drive_id", driveID).Str("drive_name", drive.Name).Logger()
 }
+
+var now = time.Now
@@ -2,17 +2,39 @@ package bernard
 
 import (
 	"database/sql"
+	"embed"
 	"errors"
 	"fmt"
 
 	"github.com/l3uddz/bernard/datastore"
 	"github.com/l3uddz/bernard/datastore/sqlite"
+
+	"github.com/cloudbox/autoscan/migrate"
 )
 
 type bds struct {
 	*sqlite.Datastore
 }
 
+var (
+	//go:embed migrations
+	migrations embed.FS
+)
+
+func newDatastore(db *sql.DB, mg *migrate.Migrator) (*bds, error) {
+	store, err := sqlite.FromDB(db)
+	if err != nil {
+		return nil, err
+	}
+
+	// migrations
+	if err := mg.Migrate(&migrations, "bernard-resync"); err != nil {
+		return nil, fmt.Errorf("migrate: %w", err)
+	}
+
+	return &bds{store}, nil
+}
+
 const sqlSelectFile = `SELECT id, name, parent, size, md5, trashed FROM file WHERE drive = $1 AND id = $2 LIMIT 1`
 
 func (d *bds) GetFile(driveID string, fileID string) (*datastore.File, error) {
@@ -72,3 +94,80 @@ func (d *bds) GetDrive(driveID string) (*datastore.Drive, error) {
 
 	return drv, nil
 }
+
+// A resyncRequest is a requested full sync of a drive.
+// Reset is set once the drive was removed from the datastore.
+type resyncRequest struct {
+	Scan  bool
+	Reset bool
+}
+
+const sqlUpsertResync = `
+INSERT INTO bernard_resync (drive, scan, reset)
+VALUES (?, ?, false)
+ON CONFLICT (drive) DO UPDATE SET
+	scan = excluded.scan OR bernard_resync.scan,
+	reset = false
+`
+
+// RequestResync requests a full sync of the drive on the next run.
+func (d *bds) RequestResync(driveID string, scan bool) error {
+	_, err := d.DB.Exec(sqlUpsertResync, driveID, scan)
+	return err
+}
+
+const sqlSelectResync = `SELECT scan, reset FROM bernard_resync WHERE drive = ?`
+
+// ResyncRequest returns the requested resync of a drive, or nil when no resync was requested.
+func (d *bds) ResyncRequest(driveID string) (*resyncRequest, error) {
+	r := new(resyncRequest)
+
+	row := d.DB.QueryRow(sqlSelectResync, driveID)
+	err := row.Scan(&r.Scan, &r.Reset)
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+
+	return r, nil
+}
+
+const sqlDeleteResync = `DELETE FROM bernard_resync WHERE drive = ? AND reset = true`
+
+// CompleteResync removes the requested resync of a drive,
+// unless the resync was requested again while the drive was syncing.
+func (d *bds) CompleteResync(driveID string) error {
+	_, err := d.DB.Exec(sqlDeleteResync, driveID)
+	return err
+}
+
+// ResetDrive removes the files, folders and page token of a drive,
+// so the drive is fully synced on the next run.
+func (d *bds) ResetDrive(driveID string) error {
+	tx, err := d.DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	statements := []string{
+		`DELETE FROM file WHERE drive = ?`,
+		`DELETE FROM folder WHERE drive = ?`,
+		`DELETE FROM drive WHERE id = ?`,
+		`UPDATE bernard_resync SET reset = true WHERE drive = ?`,
+	}
+
+	for _, stmt := range statements {
+		if _, err = tx.Exec(stmt, driveID); err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				panic(rollbackErr)
+			}
+
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,161 @@
+package bernard
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/l3uddz/bernard/datastore/sqlite"
+	_ "modernc.org/sqlite"
+
+	"github.com/cloudbox/autoscan/migrate"
+)
+
+const sqlTestSchema = `
+CREATE TABLE file (id TEXT, drive TEXT, name TEXT, parent TEXT, size INTEGER, md5 TEXT, trashed BOOLEAN, PRIMARY KEY(id, drive));
+CREATE TABLE folder (id TEXT, drive TEXT, name TEXT, trashed BOOLEAN, parent TEXT, PRIMARY KEY(id, drive));
+CREATE TABLE drive (id TEXT, pageToken TEXT, PRIMARY KEY(id));
+
+INSERT INTO folder VALUES ('a', 'a', 'Drive A', false, NULL), ('b', 'b', 'Drive B', false, NULL);
+INSERT INTO file VALUES ('f1', 'a', 'file', 'a', 1, '', false), ('f2', 'b', 'file', 'b', 1, '', false);
+INSERT INTO drive VALUES ('a', 'token'), ('b', 'token');
+`
+
+func getDatastore(t *testing.T) *bds {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db.SetMaxOpenConns(1)
+
+	// the bernard tables are created by hand as the migrations of the library are applied in random order
+	if _, err := db.Exec(sqlTestSchema); err != nil {
+		t.Fatal(err)
+	}
+
+	mg, err := migrate.New(db, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mg.Migrate(&migrations, "bernard-resync"); err != nil {
+		t.Fatal(err)
+	}
+
+	return &bds{&sqlite.Datastore{DB: db}}
+}
+
+func count(t *testing.T, store *bds, query string, driveID string) int {
+	var n int
+	if err := store.DB.QueryRow(query, driveID).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+
+	return n
+}
+
+func TestResync(t *testing.T) {
+	store := getDatastore(t)
+
+	// no request
+	r, err := store.ResyncRequest("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r != nil {
+		t.Fatalf("Expected no resync request, got: %+v", r)
+	}
+
+	// request with scan, a later request without scan keeps the scan
+	if err := store.RequestResync("a", true); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.RequestResync("a", false); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err = store.ResyncRequest("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r == nil || !r.Scan || r.Reset {
+		t.Fatalf("Expected scan without reset, got: %+v", r)
+	}
+
+	// reset only removes the state of the requested drive
+	if err := store.ResetDrive("a"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, query := range []string{
+		`SELECT COUNT(*) FROM file WHERE drive = ?`,
+		`SELECT COUNT(*) FROM folder WHERE drive = ?`,
+		`SELECT COUNT(*) FROM drive WHERE id = ?`,
+	} {
+		if n := count(t, store, query, "a"); n != 0 {
+			t.Errorf("Expected no rows for drive a: %s: got %d", query, n)
+		}
+
+		if n := count(t, store, query, "b"); n != 1 {
+			t.Errorf("Expected one row for drive b: %s: got %d", query, n)
+		}
+	}
+
+	r, err = store.ResyncRequest("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r == nil || !r.Reset {
+		t.Fatalf("Expected reset, got: %+v", r)
+	}
+
+	// a new request while the full sync is pending resets the drive again
+	if err := store.RequestResync("a", false); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err = store.ResyncRequest("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r == nil || r.Reset || !r.Scan {
+		t.Fatalf("Expected scan without reset, got: %+v", r)
+	}
+
+	// completing keeps a request which has not been reset yet
+	if err := store.CompleteResync("a"); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err = store.ResyncRequest("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r == nil {
+		t.Fatal("Expected the resync request to be kept")
+	}
+
+	// complete
+	if err := store.ResetDrive("a"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.CompleteResync("a"); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err = store.ResyncRequest("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r != nil {
+		t.Fatalf("Expected no resync request, got: %+v", r)
+	}
+}
@@ -0,0 +1,151 @@
+package bernard
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"time"
+
+	lowe "github.com/l3uddz/bernard"
+)
+
+const (
+	driveAPIURL = "https://www.googleapis.com/drive/v3"
+
+	// time between discoveries of shared drives
+	defaultDiscoveryInterval = time.Hour
+)
+
+type sharedDrive struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type discovery struct {
+	auth     lowe.Authenticator
+	client   *http.Client
+	baseURL  string
+	limiter  *rateLimiter
+	interval time.Duration
+	include  []*regexp.Regexp
+	exclude  []*regexp.Regexp
+}
+
+func newDiscovery(auth lowe.Authenticator, client *http.Client, limiter *rateLimiter, interval time.Duration, includes []string, excludes []string) (*discovery, error) {
+	compile := func(patterns []string) ([]*regexp.Regexp, error) {
+		res := make([]*regexp.Regexp, 0, len(patterns))
+		for _, p := range patterns {
+			re, err := regexp.Compile(p)
+			if err != nil {
+				return nil, err
+			}
+
+			res = append(res, re)
+		}
+
+		return res, nil
+	}
+
+	include, err := compile(includes)
+	if err != nil {
+		return nil, fmt.Errorf("include: %w", err)
+	}
+
+	exclude, err := compile(excludes)
+	if err != nil {
+		return nil, fmt.Errorf("exclude: %w", err)
+	}
+
+	if interval <= 0 {
+		interval = defaultDiscoveryInterval
+	}
+
+	return &discovery{
+		auth:     auth,
+		client:   client,
+		baseURL:  driveAPIURL,
+		limiter:  limiter,
+		interval: interval,
+		include:  include,
+		exclude:  exclude,
+	}, nil
+}
+
+// Allowed returns whether a shared drive matches the filters, by either its name or its ID.
+// Excludes take precedence over includes.
+func (d *discovery) Allowed(drive sharedDrive) bool {
+	matches := func(res []*regexp.Regexp) bool {
+		for _, re := range res {
+			if re.MatchString(drive.Name) || re.MatchString(drive.ID) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if matches(d.exclude) {
+		return false
+	}
+
+	return len(d.include) == 0 || matches(d.include)
+}
+
+// Drives lists every shared drive the account has access to.
+func (d *discovery) Drives() ([]sharedDrive, error) {
+	drives := make([]sharedDrive, 0)
+	pageToken := ""
+
+	for {
+		q := url.Values{}
+		q.Set("pageSize", "100")
+		q.Set("fields", "nextPageToken,drives(id,name)")
+		if pageToken != "" {
+			q.Set("pageToken", pageToken)
+		}
+
+		req, err := http.NewRequest("GET", d.baseURL+"/drives?"+q.Encode(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed creating request: %w", err)
+		}
+
+		d.limiter.Wait()
+
+		token, _, err := d.auth.AccessToken()
+		if err != nil {
+			return nil, fmt.Errorf("access token: %w", err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		res, err := d.client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("list drives: %w", err)
+		}
+
+		type Response struct {
+			NextPageToken string        `json:"nextPageToken"`
+			Drives        []sharedDrive `json:"drives"`
+		}
+
+		resp := new(Response)
+		err = json.NewDecoder(res.Body).Decode(resp)
+		res.Body.Close()
+
+		switch {
+		case res.StatusCode != 200:
+			return nil, fmt.Errorf("list drives: %s", res.Status)
+		case err != nil:
+			return nil, fmt.Errorf("failed decoding drives: %w", err)
+		}
+
+		drives = append(drives, resp.Drives...)
+		if resp.NextPageToken == "" {
+			return drives, nil
+		}
+
+		pageToken = resp.NextPageToken
+	}
+}
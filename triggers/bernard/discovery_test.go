@@ -0,0 +1,58 @@
+package bernard
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+type staticAuth string
+
+func (a staticAuth) AccessToken() (string, int64, error) {
+	return string(a), 0, nil
+}
+
+func TestDiscovery(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.Query().Get("pageToken") {
+		case "":
+			_, _ = w.Write([]byte(`{"nextPageToken": "2", "drives": [{"id": "0A1", "name": "Movies"}, {"id": "0A2", "name": "TV"}]}`))
+		case "2":
+			_, _ = w.Write([]byte(`{"drives": [{"id": "0A3", "name": "Backups"}, {"id": "0A4", "name": "Music"}]}`))
+		}
+	}))
+	defer server.Close()
+
+	d, err := newDiscovery(staticAuth("token"), http.DefaultClient, newRateLimiter(RateLimit{Requests: 8, Burst: 8, Syncs: 5}), 0, []string{"^Movies$", "^TV$", "^0A3$"}, []string{"^Backups$"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d.baseURL = server.URL
+
+	drives, err := d.Drives()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	allowed := make([]string, 0)
+	for _, drive := range drives {
+		if d.Allowed(drive) {
+			allowed = append(allowed, drive.ID)
+		}
+	}
+
+	// excludes take precedence over includes by ID
+	expected := []string{"0A1", "0A2"}
+	if !reflect.DeepEqual(allowed, expected) {
+		t.Logf("want: %v", expected)
+		t.Logf("got:  %v", allowed)
+		t.Errorf("Allowed drives do not equal")
+	}
+}
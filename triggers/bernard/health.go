@@ -0,0 +1,92 @@
+package bernard
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DriveHealth describes the sync state of a drive.
+type DriveHealth struct {
+	ID                  string    `json:"id"`
+	LastSuccess         time.Time `json:"last_success"`
+	LastError           string    `json:"last_error,omitempty"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	NextAttempt         time.Time `json:"next_attempt"`
+	Stopped             bool      `json:"stopped"`
+}
+
+// ErrDriveNotFound is returned when no sync job exists for a drive.
+var ErrDriveNotFound = errors.New("bernard: drive not found")
+
+var (
+	jobs    = make(map[string]*syncJob)
+	jobLock = &sync.Mutex{}
+)
+
+func registerJob(driveID string, job *syncJob) {
+	jobLock.Lock()
+	defer jobLock.Unlock()
+
+	jobs[driveID] = job
+}
+
+func unregisterJob(driveID string) {
+	jobLock.Lock()
+	defer jobLock.Unlock()
+
+	delete(jobs, driveID)
+}
+
+// Health returns the sync state of every drive, sorted by drive ID.
+func Health() []DriveHealth {
+	jobLock.Lock()
+	defer jobLock.Unlock()
+
+	health := make([]DriveHealth, 0, len(jobs))
+	for id, job := range jobs {
+		health = append(health, job.health(id))
+	}
+
+	sort.Slice(health, func(i, j int) bool {
+		return health[i].ID < health[j].ID
+	})
+
+	return health
+}
+
+// Resume resets the backoff of a drive, and restarts the drive when it was stopped.
+// The drive is synced on the next run of its schedule.
+func Resume(driveID string) error {
+	jobLock.Lock()
+	job, ok := jobs[driveID]
+	jobLock.Unlock()
+
+	if !ok {
+		return ErrDriveNotFound
+	}
+
+	job.resume()
+	return nil
+}
+
+// Resync requests a full sync of a drive on the next run of its schedule,
+// optionally followed by a scan of the root of the drive.
+// A drive which is backing off or stopped is resumed as well.
+func Resync(driveID string, scan bool) error {
+	jobLock.Lock()
+	job, ok := jobs[driveID]
+	jobLock.Unlock()
+
+	if !ok {
+		return ErrDriveNotFound
+	}
+
+	if err := job.resync(scan); err != nil {
+		return err
+	}
+
+	job.resume()
+	return nil
+}
@@ -0,0 +1,113 @@
+package bernard
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	lowe "github.com/l3uddz/bernard"
+	"github.com/rs/zerolog"
+
+	"github.com/cloudbox/autoscan"
+)
+
+func TestBackoff(t *testing.T) {
+	expected := []time.Duration{
+		time.Minute, 2 * time.Minute, 4 * time.Minute, 8 * time.Minute, 16 * time.Minute,
+		32 * time.Minute, time.Hour, time.Hour,
+	}
+
+	for i, want := range expected {
+		if got := backoff(i+1, time.Hour); got != want {
+			t.Errorf("Backoff after %d failures: want %v, got %v", i+1, want, got)
+		}
+	}
+}
+
+func TestSyncJob(t *testing.T) {
+	currentTime := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time {
+		return currentTime
+	}
+	defer func() {
+		now = time.Now
+	}()
+
+	var err error
+	runs := 0
+
+	job := newSyncJob(zerolog.Nop(), time.Hour, func() error {
+		runs++
+		return err
+	})
+
+	registerJob("drive", job)
+	defer unregisterJob("drive")
+
+	// failures back off
+	err = fmt.Errorf("partial sync: %w", lowe.ErrNetwork)
+	job.Run()
+	job.Run()
+
+	if runs != 1 {
+		t.Errorf("Expected the second run to be skipped, got %d runs", runs)
+	}
+
+	currentTime = currentTime.Add(time.Minute)
+	job.Run()
+
+	h := Health()[0]
+	if runs != 2 || h.ConsecutiveFailures != 2 || !h.NextAttempt.Equal(currentTime.Add(2*time.Minute)) {
+		t.Errorf("Unexpected health after failures: %+v", h)
+	}
+
+	// success resets the failures
+	err = nil
+	currentTime = currentTime.Add(2 * time.Minute)
+	job.Run()
+
+	h = Health()[0]
+	if h.ConsecutiveFailures != 0 || !h.LastSuccess.Equal(currentTime) || h.LastError != "" {
+		t.Errorf("Unexpected health after success: %+v", h)
+	}
+
+	// fatal errors are retried with backoff
+	err = fmt.Errorf("processor: %w", autoscan.ErrFatal)
+	job.Run()
+
+	currentTime = currentTime.Add(time.Minute)
+	job.Run()
+
+	h = Health()[0]
+	if runs != 5 || h.Stopped || h.ConsecutiveFailures != 2 {
+		t.Errorf("Expected fatal errors to be retried, got %d runs: %+v", runs, h)
+	}
+
+	// inaccessible drives are stopped until resumed
+	err = fmt.Errorf("partial sync: %w", lowe.ErrNotFound)
+	currentTime = currentTime.Add(2 * time.Minute)
+	job.Run()
+
+	currentTime = currentTime.Add(24 * time.Hour)
+	job.Run()
+
+	if runs != 6 || !Health()[0].Stopped {
+		t.Errorf("Expected the drive to be stopped, got %d runs", runs)
+	}
+
+	if err := Resume("drive"); err != nil {
+		t.Fatal(err)
+	}
+
+	err = nil
+	job.Run()
+
+	if runs != 7 || Health()[0].Stopped {
+		t.Errorf("Expected the drive to be resumed, got %d runs", runs)
+	}
+
+	if err := Resume("unknown"); !errors.Is(err, ErrDriveNotFound) {
+		t.Errorf("Expected ErrDriveNotFound, got: %v", err)
+	}
+}
@@ -1,10 +1,16 @@
 package bernard
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
 	"sync"
 	"time"
 
+	"github.com/rs/zerolog"
 	"golang.org/x/sync/semaphore"
 	"golang.org/x/time/rate"
 )
@@ -14,12 +20,28 @@ const (
 	requestLimit = 8
 	// how many drives can run at once (at the trigger level), e.g. 2 triggers, with 5 drives each.
 	syncLimit = 5
+
+	// the request rate is never slowed down below this fraction of the configured rate
+	minRateFactor = 8
+	// time without rate limit responses before the request rate is increased again
+	recoverInterval = time.Minute
 )
 
+// RateLimit configures the requests and drive syncs of an account.
+type RateLimit struct {
+	Requests float64 `yaml:"requests"`
+	Burst    int     `yaml:"burst"`
+	Syncs    int     `yaml:"syncs"`
+}
+
 type rateLimiter struct {
 	ctx context.Context
 	rl  *rate.Limiter
 	sem *semaphore.Weighted
+
+	config     RateLimit
+	mtx        sync.Mutex
+	lastChange time.Time
 }
 
 func (r *rateLimiter) Wait() {
@@ -34,11 +56,49 @@ func (r *rateLimiter) Release(n int64) {
 	r.sem.Release(n)
 }
 
-func newRateLimiter() *rateLimiter {
+// Slowdown halves the request rate after a rate limit response,
+// and returns the new rate.
+func (r *rateLimiter) Slowdown() rate.Limit {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	limit := r.rl.Limit() / 2
+	if floor := rate.Limit(r.config.Requests / minRateFactor); limit < floor {
+		limit = floor
+	}
+
+	r.rl.SetLimit(limit)
+	r.lastChange = now()
+	return limit
+}
+
+// Recover gradually increases the request rate back to the configured rate
+// once no rate limit responses were received for a while.
+func (r *rateLimiter) Recover() {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	configured := rate.Limit(r.config.Requests)
+	limit := r.rl.Limit()
+	if limit >= configured || now().Sub(r.lastChange) < recoverInterval {
+		return
+	}
+
+	limit += configured / 10
+	if limit > configured {
+		limit = configured
+	}
+
+	r.rl.SetLimit(limit)
+	r.lastChange = now()
+}
+
+func newRateLimiter(c RateLimit) *rateLimiter {
 	return &rateLimiter{
-		ctx: context.Background(),
-		rl:  rate.NewLimiter(rate.Every(time.Second/time.Duration(requestLimit)), requestLimit),
-		sem: semaphore.NewWeighted(int64(syncLimit)),
+		ctx:    context.Background(),
+		rl:     rate.NewLimiter(rate.Limit(c.Requests), c.Burst),
+		sem:    semaphore.NewWeighted(int64(c.Syncs)),
+		config: c,
 	}
 }
 
@@ -47,18 +107,115 @@ var (
 	lock     = &sync.Mutex{}
 )
 
-func getRateLimiter(account string) (*rateLimiter, error) {
+func getRateLimiter(account string, c RateLimit) (*rateLimiter, error) {
+	if c.Requests <= 0 {
+		c.Requests = requestLimit
+	}
+
+	if c.Burst <= 0 {
+		c.Burst = int(c.Requests)
+		if c.Burst < 1 {
+			c.Burst = 1
+		}
+	}
+
+	if c.Syncs <= 0 {
+		c.Syncs = syncLimit
+	}
+
 	lock.Lock()
 	defer lock.Unlock()
 
 	// return existing limiter for the account
 	if limiter, ok := limiters[account]; ok {
+		if limiter.config != c {
+			return nil, fmt.Errorf("%v: rate limit differs from another trigger using the same account", account)
+		}
+
 		return limiter, nil
 	}
 
 	// add limiter to map
-	limiter := newRateLimiter()
+	limiter := newRateLimiter(c)
 	limiters[account] = limiter
 
 	return limiter, nil
 }
+
+// rateLimitTransport slows down the requests of an account when Google Drive responds with a rate limit error.
+type rateLimitTransport struct {
+	base    http.RoundTripper
+	limiter *rateLimiter
+	log     zerolog.Logger
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isRateLimited(res) {
+		t.limiter.Recover()
+		return res, nil
+	}
+
+	limit := t.limiter.Slowdown()
+	t.log.Warn().
+		Float64("requests", float64(limit)).
+		Msg("Rate limit exceeded, slowing down requests")
+
+	return res, nil
+}
+
+// isRateLimited returns whether a response is a rate limit error.
+// The body of 403 responses is read to determine the reason, and replaced afterwards.
+func isRateLimited(res *http.Response) bool {
+	switch res.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusForbidden:
+	default:
+		return false
+	}
+
+	b, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	res.Body = io.NopCloser(bytes.NewReader(b))
+	if err != nil {
+		return false
+	}
+
+	response := struct {
+		Error struct {
+			Errors []struct {
+				Reason string `json:"reason"`
+			} `json:"errors"`
+		} `json:"error"`
+	}{}
+
+	if err := json.Unmarshal(b, &response); err != nil {
+		return false
+	}
+
+	for _, e := range response.Error.Errors {
+		switch e.Reason {
+		case "userRateLimitExceeded", "rateLimitExceeded":
+			return true
+		}
+	}
+
+	return false
+}
+
+// newRateLimitClient creates the HTTP client for the requests of an account.
+func newRateLimitClient(limiter *rateLimiter, log zerolog.Logger) *http.Client {
+	return &http.Client{
+		Timeout: 15 * time.Second,
+		Transport: &rateLimitTransport{
+			base:    http.DefaultTransport,
+			limiter: limiter,
+			log:     log,
+		},
+	}
+}
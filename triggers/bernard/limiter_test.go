@@ -0,0 +1,103 @@
+package bernard
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"golang.org/x/time/rate"
+)
+
+func TestRateLimitTransport(t *testing.T) {
+	currentTime := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time {
+		return currentTime
+	}
+	defer func() {
+		now = time.Now
+	}()
+
+	status := http.StatusOK
+	body := `{}`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	limiter := newRateLimiter(RateLimit{Requests: 8, Burst: 8, Syncs: 5})
+	client := newRateLimitClient(limiter, zerolog.Nop())
+
+	get := func() {
+		res, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the body must remain readable
+		b, err := io.ReadAll(res.Body)
+		if err != nil || string(b) != body {
+			t.Errorf("Unexpected body: %q", b)
+		}
+
+		res.Body.Close()
+	}
+
+	// other 403 errors do not slow down
+	status = http.StatusForbidden
+	body = `{"error": {"errors": [{"reason": "insufficientFilePermissions"}]}}`
+	get()
+
+	if limiter.rl.Limit() != 8 {
+		t.Errorf("Expected a rate of 8, got %v", limiter.rl.Limit())
+	}
+
+	// rate limit errors halve the rate down to the floor
+	body = `{"error": {"errors": [{"reason": "userRateLimitExceeded"}]}}`
+	for _, want := range []rate.Limit{4, 2, 1, 1} {
+		get()
+
+		if limiter.rl.Limit() != want {
+			t.Errorf("Expected a rate of %v, got %v", want, limiter.rl.Limit())
+		}
+	}
+
+	// the rate recovers gradually without rate limit errors
+	status = http.StatusOK
+	body = `{}`
+	get()
+
+	if limiter.rl.Limit() != 1 {
+		t.Errorf("Expected no recovery within the recover interval, got %v", limiter.rl.Limit())
+	}
+
+	currentTime = currentTime.Add(recoverInterval)
+	get()
+
+	if limiter.rl.Limit() != 1.8 {
+		t.Errorf("Expected a rate of 1.8, got %v", limiter.rl.Limit())
+	}
+}
+
+func TestGetRateLimiter(t *testing.T) {
+	limiter, err := getRateLimiter("test@example.com", RateLimit{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if limiter.config != (RateLimit{Requests: requestLimit, Burst: requestLimit, Syncs: syncLimit}) {
+		t.Errorf("Unexpected default rate limit: %+v", limiter.config)
+	}
+
+	if _, err := getRateLimiter("test@example.com", RateLimit{Requests: requestLimit}); err != nil {
+		t.Errorf("Expected the existing limiter, got: %v", err)
+	}
+
+	if _, err := getRateLimiter("test@example.com", RateLimit{Requests: 2}); err == nil {
+		t.Errorf("Expected an error for a conflicting rate limit")
+	}
+}
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS bernard_resync (
+    "drive" TEXT NOT NULL,
+    "scan" BOOLEAN NOT NULL,
+    "reset" BOOLEAN NOT NULL,
+    PRIMARY KEY(drive)
+)
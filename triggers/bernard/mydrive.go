@@ -0,0 +1,322 @@
+package bernard
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	lowe "github.com/l3uddz/bernard"
+	ds "github.com/l3uddz/bernard/datastore"
+)
+
+const (
+	// alias of the root folder of a My Drive, resolved to the ID of the folder on start
+	myDriveID = "root"
+
+	folderMimeType = "application/vnd.google-apps.folder"
+
+	// attempts of a request which failed with a rate limit or server error
+	maxRequestAttempts = 5
+)
+
+// syncer synchronises the content of a drive to the datastore.
+type syncer interface {
+	FullSync(driveID string) error
+	PartialSync(driveID string, hooks ...lowe.Hook) error
+}
+
+type driveFile struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	MimeType    string   `json:"mimeType"`
+	Parents     []string `json:"parents"`
+	Size        uint64   `json:"size,string"`
+	MD5Checksum string   `json:"md5Checksum"`
+	Trashed     bool     `json:"trashed"`
+	DriveID     string   `json:"driveId"`
+}
+
+// myDrive syncs the My Drive of the account, the Bernard library only supports shared drives.
+// The root folder of the My Drive acts as the drive in the datastore.
+type myDrive struct {
+	auth      lowe.Authenticator
+	client    *http.Client
+	baseURL   string
+	limiter   *rateLimiter
+	store     *bds
+	safeSleep time.Duration
+	sleep     func(time.Duration)
+}
+
+func newMyDrive(auth lowe.Authenticator, client *http.Client, limiter *rateLimiter, store *bds, safeSleep time.Duration) *myDrive {
+	return &myDrive{
+		auth:      auth,
+		client:    client,
+		baseURL:   driveAPIURL,
+		limiter:   limiter,
+		store:     store,
+		safeSleep: safeSleep,
+		sleep:     time.Sleep,
+	}
+}
+
+// get requests a path of the Drive API and decodes the response into v.
+// Rate limit and server errors are retried with a backoff.
+func (m *myDrive) get(path string, q url.Values, v interface{}) error {
+	wait := time.Second
+
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequest("GET", m.baseURL+path+"?"+q.Encode(), nil)
+		if err != nil {
+			return fmt.Errorf("failed creating request: %w", err)
+		}
+
+		m.limiter.Wait()
+
+		token, _, err := m.auth.AccessToken()
+		if err != nil {
+			return fmt.Errorf("access token: %v: %w", err, lowe.ErrInvalidCredentials)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		res, err := m.client.Do(req)
+		if err != nil {
+			return fmt.Errorf("%v: %w", err, lowe.ErrNetwork)
+		}
+
+		retry := isRateLimited(res) || res.StatusCode >= 500
+		if res.StatusCode == 200 {
+			err = json.NewDecoder(res.Body).Decode(v)
+		}
+		res.Body.Close()
+
+		switch {
+		case res.StatusCode == 200 && err != nil:
+			return fmt.Errorf("decoding %v: %w", path, err)
+		case res.StatusCode == 200:
+			return nil
+		case retry && attempt < maxRequestAttempts:
+			m.sleep(wait)
+			wait *= 2
+			continue
+		case res.StatusCode == 401:
+			return fmt.Errorf("%v: %w", path, lowe.ErrInvalidCredentials)
+		case res.StatusCode == 404:
+			return fmt.Errorf("%v: %w", path, lowe.ErrNotFound)
+		default:
+			return fmt.Errorf("%v: %s: %w", path, res.Status, lowe.ErrNetwork)
+		}
+	}
+}
+
+// Root returns the root folder of the My Drive.
+func (m *myDrive) Root() (*driveFile, error) {
+	q := url.Values{}
+	q.Set("fields", "id,name")
+
+	root := new(driveFile)
+	if err := m.get("/files/"+myDriveID, q, root); err != nil {
+		return nil, err
+	}
+
+	return root, nil
+}
+
+func (m *myDrive) pageToken() (string, error) {
+	response := struct {
+		StartPageToken string `json:"startPageToken"`
+	}{}
+
+	if err := m.get("/changes/startPageToken", url.Values{}, &response); err != nil {
+		return "", err
+	}
+
+	return response.StartPageToken, nil
+}
+
+// FullSync stores every file and folder owned by the account.
+func (m *myDrive) FullSync(driveID string) error {
+	pageToken, err := m.pageToken()
+	if err != nil {
+		return err
+	}
+
+	// prevent missing changes made while listing the drive
+	if m.safeSleep > 0 {
+		m.sleep(m.safeSleep)
+	}
+
+	root, err := m.Root()
+	if err != nil {
+		return err
+	}
+
+	var items []driveFile
+	nextPageToken := ""
+
+	for {
+		q := url.Values{}
+		q.Set("corpora", "user")
+		q.Set("spaces", "drive")
+		q.Set("q", "'me' in owners")
+		q.Set("pageSize", "1000")
+		q.Set("fields", "nextPageToken,files(id,name,mimeType,parents,md5Checksum,size,trashed)")
+		if nextPageToken != "" {
+			q.Set("pageToken", nextPageToken)
+		}
+
+		response := struct {
+			NextPageToken string      `json:"nextPageToken"`
+			Files         []driveFile `json:"files"`
+		}{}
+
+		if err := m.get("/files", q, &response); err != nil {
+			return err
+		}
+
+		items = append(items, response.Files...)
+		if response.NextPageToken == "" {
+			break
+		}
+
+		nextPageToken = response.NextPageToken
+	}
+
+	folders, files := convertFiles(items)
+	drive := ds.Drive{
+		ID:        driveID,
+		Name:      root.Name,
+		PageToken: pageToken,
+	}
+
+	return m.store.FullSync(drive, ds.OrderFoldersOnHierarchy(folders), files)
+}
+
+// PartialSync stores the changes within the My Drive hierarchy since the last sync.
+func (m *myDrive) PartialSync(driveID string, hooks ...lowe.Hook) error {
+	pageToken, err := m.store.PageToken(driveID)
+	if err != nil {
+		return err
+	}
+
+	var changed []driveFile
+	var removed []string
+
+	drive := ds.Drive{ID: driveID}
+	nextPageToken := pageToken
+
+	for nextPageToken != "" {
+		q := url.Values{}
+		q.Set("pageToken", nextPageToken)
+		q.Set("spaces", "drive")
+		q.Set("restrictToMyDrive", "true")
+		q.Set("pageSize", "1000")
+		q.Set("fields", "nextPageToken,newStartPageToken,changes(fileId,removed,file(id,driveId,name,mimeType,parents,md5Checksum,size,trashed))")
+
+		response := struct {
+			NextPageToken     string `json:"nextPageToken"`
+			NewStartPageToken string `json:"newStartPageToken"`
+			Changes           []struct {
+				FileID  string    `json:"fileId"`
+				Removed bool      `json:"removed"`
+				File    driveFile `json:"file"`
+			} `json:"changes"`
+		}{}
+
+		if err := m.get("/changes", q, &response); err != nil {
+			return err
+		}
+
+		for _, change := range response.Changes {
+			switch {
+			case change.FileID == "":
+				continue
+			case change.Removed || change.File.DriveID != "":
+				// removals are reported for items which were never stored, such as files shared with the account
+				stored, err := m.stored(driveID, change.FileID)
+				if err != nil {
+					return err
+				}
+
+				if stored {
+					removed = append(removed, change.FileID)
+				}
+			default:
+				changed = append(changed, change.File)
+			}
+		}
+
+		nextPageToken = response.NextPageToken
+		drive.PageToken = response.NewStartPageToken
+	}
+
+	if drive.PageToken == pageToken {
+		return nil
+	}
+
+	folders, files := convertFiles(changed)
+	folders = ds.OrderFoldersOnHierarchy(folders)
+
+	for _, hook := range hooks {
+		if err := hook(drive, files, folders, removed); err != nil {
+			return err
+		}
+	}
+
+	return m.store.PartialSync(drive, folders, files, removed)
+}
+
+// stored returns whether a file or folder is in the datastore.
+func (m *myDrive) stored(driveID string, id string) (bool, error) {
+	_, err := m.store.GetFile(driveID, id)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err == nil, err
+	}
+
+	_, err = m.store.GetFolder(driveID, id)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err == nil, err
+	}
+
+	return false, nil
+}
+
+// convertFiles splits the items into folders and files.
+// Items without parents, such as files shared with the account, are kept without a parent.
+func convertFiles(items []driveFile) ([]ds.Folder, []ds.File) {
+	folders := make([]ds.Folder, 0)
+	files := make([]ds.File, 0)
+
+	for _, item := range items {
+		parent := ""
+		if len(item.Parents) > 0 {
+			parent = item.Parents[0]
+		}
+
+		if item.MimeType == folderMimeType {
+			folders = append(folders, ds.Folder{
+				ID:      item.ID,
+				Name:    item.Name,
+				Parent:  parent,
+				Trashed: item.Trashed,
+			})
+			continue
+		}
+
+		files = append(files, ds.File{
+			ID:      item.ID,
+			Name:    item.Name,
+			Parent:  parent,
+			Trashed: item.Trashed,
+			Size:    item.Size,
+			MD5:     item.MD5Checksum,
+		})
+	}
+
+	return folders, files
+}
@@ -0,0 +1,95 @@
+package bernard
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestMyDrive(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+
+		switch {
+		case r.URL.Path == "/changes/startPageToken":
+			_, _ = w.Write([]byte(`{"startPageToken": "1"}`))
+		case r.URL.Path == "/files/root":
+			_, _ = w.Write([]byte(`{"id": "R", "name": "My Drive"}`))
+		case r.URL.Path == "/files" && q.Get("pageToken") == "":
+			_, _ = w.Write([]byte(`{"nextPageToken": "2", "files": [
+				{"id": "tv", "name": "TV", "mimeType": "application/vnd.google-apps.folder", "parents": ["R"]},
+				{"id": "show", "name": "Show", "mimeType": "application/vnd.google-apps.folder", "parents": ["tv"]},
+				{"id": "s01", "name": "Season 1", "mimeType": "application/vnd.google-apps.folder", "parents": ["show"]},
+				{"id": "e1", "name": "e1.mkv", "mimeType": "video/x-matroska", "parents": ["s01"], "size": "100"}
+			]}`))
+		case r.URL.Path == "/files" && q.Get("pageToken") == "2":
+			_, _ = w.Write([]byte(`{"files": [
+				{"id": "orphan", "name": "orphan.mkv", "mimeType": "video/x-matroska", "size": "100"},
+				{"id": "elsewhere", "name": "Elsewhere", "mimeType": "application/vnd.google-apps.folder", "parents": ["X"]}
+			]}`))
+		case r.URL.Path == "/changes" && q.Get("pageToken") == "1":
+			_, _ = w.Write([]byte(`{"nextPageToken": "2", "changes": [
+				{"fileId": "e2", "file": {"id": "e2", "name": "e2.mkv", "mimeType": "video/x-matroska", "parents": ["s01"], "size": "100"}}
+			]}`))
+		case r.URL.Path == "/changes" && q.Get("pageToken") == "2":
+			_, _ = w.Write([]byte(`{"newStartPageToken": "3", "changes": [
+				{"fileId": "shared", "removed": true},
+				{"fileId": "e3", "file": {"id": "e3", "name": "e3.mkv", "mimeType": "video/x-matroska", "parents": ["elsewhere"], "size": "100"}},
+				{"fileId": "e4", "file": {"id": "e4", "name": "e4.mkv", "mimeType": "video/x-matroska", "parents": ["unknown"], "size": "100"}}
+			]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	store := getDatastore(t)
+	md := newMyDrive(staticAuth("token"), http.DefaultClient, newRateLimiter(RateLimit{Requests: 8, Burst: 8, Syncs: 5}), store, 0)
+	md.baseURL = server.URL
+
+	root, err := md.Root()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := md.FullSync(root.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	drive, err := store.GetDrive("R")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if drive.Name != "My Drive" || drive.PageToken != "1" {
+		t.Errorf("Unexpected drive: %+v", drive)
+	}
+
+	// changes of items outside of the drive do not result in paths
+	dh, diff := store.NewDifferencesHook()
+	ph := NewPostProcessBernardDiff("R", store, diff)
+	ch, paths := NewPathsHook("R", true, store, diff, RollUp{})
+
+	if err := md.PartialSync("R", dh, ph, ch); err != nil {
+		t.Fatal(err)
+	}
+
+	sort.Strings(paths.NewFolders)
+	expected := []string{"/TV/Show/Season 1"}
+	if !reflect.DeepEqual(paths.NewFolders, expected) {
+		t.Logf("want: %v", expected)
+		t.Logf("got:  %v", paths.NewFolders)
+		t.Errorf("New folders do not equal")
+	}
+
+	token, err := store.PageToken("R")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if token != "3" {
+		t.Errorf("Expected page token 3, got: %v", token)
+	}
+}
@@ -1,36 +1,66 @@
 package bernard
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
+	"path"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/l3uddz/bernard"
 	"github.com/l3uddz/bernard/datastore"
 	"github.com/l3uddz/bernard/datastore/sqlite"
 )
 
+// errOutsideDrive indicates that the ancestors of a folder do not lead to the root of a My Drive.
+// Items of a My Drive can live in folders owned by others, or have no parent at all.
+// Within shared drives, every folder leads to the root, so a missing folder remains an error.
+var errOutsideDrive = errors.New("folder outside of drive")
+
 type Paths struct {
 	NewFolders []string
 	OldFolders []string
 }
 
-func NewPathsHook(driveID string, store *bds, diff *sqlite.Difference) (bernard.Hook, *Paths) {
+// RollUp controls how the changed folders of a sync are collapsed into scans.
+type RollUp struct {
+	// Depth is the number of levels below the drive root, or a library root,
+	// above which changes are never rolled up. A depth of 0 does not limit the roll-up.
+	Depth int
+
+	// Libraries are the paths within the drive the depth is counted from.
+	Libraries []string
+
+	// FileParents disables the roll-up, every parent folder of a changed file is scanned.
+	FileParents bool
+}
+
+func NewPathsHook(driveID string, myDrive bool, store *bds, diff *sqlite.Difference, rollUp RollUp) (bernard.Hook, *Paths) {
 	var paths Paths
 
 	hook := func(drive datastore.Drive, files []datastore.File, folders []datastore.Folder, removed []string) error {
 		// get folders from diff (that we are interested in)
-		parents, err := getDiffFolders(store, driveID, diff)
+		parents, err := getDiffFolders(store, driveID, myDrive, diff)
 		if err != nil {
 			return fmt.Errorf("getting parents: %w", err)
 		}
 
-		// get roots from folders
-		rootNewFolders, _ := datastore.RootFolders(parents.New)
-		rootOldFolders, _ := datastore.RootFolders(parents.Old)
+		// get roots from folders, a limited roll-up is applied to the paths instead
+		rootNewFolders, rootOldFolders := parents.New, parents.Old
+		if rollUp.Depth <= 0 && !rollUp.FileParents {
+			rootNewFolders, _ = datastore.RootFolders(parents.New)
+			rootOldFolders, _ = datastore.RootFolders(parents.Old)
+		}
 
 		// get new/changed paths
 		for _, folder := range rootNewFolders {
-			p, err := getFolderPath(store, driveID, folder.ID, parents.FolderMaps.Current)
+			p, err := getFolderPath(store, driveID, myDrive, folder.ID, parents.FolderMaps.Current)
+			if errors.Is(err, errOutsideDrive) {
+				continue
+			}
+
 			if err != nil {
 				return fmt.Errorf("building folder path: %v: %w", folder.ID, err)
 			}
@@ -40,7 +70,11 @@ func NewPathsHook(driveID string, store *bds, diff *sqlite.Difference) (bernard.
 
 		// get removed paths
 		for _, folder := range rootOldFolders {
-			p, err := getFolderPath(store, driveID, folder.ID, parents.FolderMaps.Old)
+			p, err := getFolderPath(store, driveID, myDrive, folder.ID, parents.FolderMaps.Old)
+			if errors.Is(err, errOutsideDrive) {
+				continue
+			}
+
 			if err != nil {
 				return fmt.Errorf("building old folder path: %v: %w", folder.ID, err)
 			}
@@ -48,12 +82,100 @@ func NewPathsHook(driveID string, store *bds, diff *sqlite.Difference) (bernard.
 			paths.OldFolders = append(paths.OldFolders, p)
 		}
 
+		if rollUp.Depth > 0 && !rollUp.FileParents {
+			paths.NewFolders = rollUp.Collapse(paths.NewFolders)
+			paths.OldFolders = rollUp.Collapse(paths.OldFolders)
+		}
+
 		return nil
 	}
 
 	return hook, &paths
 }
 
+// Collapse removes every path which is covered by an ancestor within the given paths,
+// as long as that ancestor is at least Depth levels below the drive root or a library root.
+// A path above the depth never covers its descendants, the changes within the folder itself
+// are scanned along with the ancestors of its changed descendants at the depth.
+func (r RollUp) Collapse(paths []string) []string {
+	sorted := append([]string{}, paths...)
+	sort.Strings(sorted)
+
+	expanded := make([]string, 0, len(sorted))
+	for _, p := range sorted {
+		expanded = append(expanded, p)
+		if r.depth(p) >= r.Depth {
+			continue
+		}
+
+		for _, d := range sorted {
+			if !within(d, p) {
+				continue
+			}
+
+			if depth := r.depth(d); depth >= r.Depth {
+				expanded = append(expanded, ancestor(d, depth-r.Depth))
+			}
+		}
+	}
+
+	sort.Strings(expanded)
+
+	collapsed := make([]string, 0, len(expanded))
+	for _, p := range expanded {
+		covered := false
+		for _, c := range collapsed {
+			if p == c || (within(p, c) && r.depth(c) >= r.Depth) {
+				covered = true
+				break
+			}
+		}
+
+		if !covered {
+			collapsed = append(collapsed, p)
+		}
+	}
+
+	return collapsed
+}
+
+// within returns whether p is a descendant of dir.
+func within(p string, dir string) bool {
+	return p != dir && (dir == "/" || strings.HasPrefix(p, dir+"/"))
+}
+
+// ancestor returns the ancestor of a path the given number of levels up.
+func ancestor(p string, levels int) string {
+	for i := 0; i < levels; i++ {
+		p = path.Dir(p)
+	}
+
+	return p
+}
+
+// depth returns the number of levels of a path below the closest library root, or the drive root.
+// Paths above a library root have a depth of 0.
+func (r RollUp) depth(p string) int {
+	root := "/"
+	for _, library := range r.Libraries {
+		library = path.Join("/", library)
+
+		switch {
+		case p == "/" || strings.HasPrefix(library, p+"/"):
+			return 0
+		case (p == library || strings.HasPrefix(p, library+"/")) && len(library) > len(root):
+			root = library
+		}
+	}
+
+	rel := strings.Trim(strings.TrimPrefix(p, root), "/")
+	if rel == "" {
+		return 0
+	}
+
+	return strings.Count(rel, "/") + 1
+}
+
 type diffFolderMaps struct {
 	Current map[string]datastore.Folder
 	Old     map[string]datastore.Folder
@@ -89,7 +211,7 @@ type Parents struct {
 	FolderMaps *diffFolderMaps
 }
 
-func getDiffFolders(store *bds, driveId string, diff *sqlite.Difference) (*Parents, error) {
+func getDiffFolders(store *bds, driveId string, myDrive bool, diff *sqlite.Difference) (*Parents, error) {
 	folderMaps := getDiffFolderMaps(diff)
 
 	newParents := make(map[string]datastore.Folder)
@@ -108,8 +230,11 @@ func getDiffFolders(store *bds, driveId string, diff *sqlite.Difference) (*Paren
 
 	// added files
 	for _, file := range diff.AddedFiles {
-		folder, err := getFolder(store, driveId, file.Parent, folderMaps.Current)
-		if err != nil {
+		folder, err := getFolder(store, driveId, myDrive, file.Parent, folderMaps.Current)
+		switch {
+		case errors.Is(err, errOutsideDrive):
+			continue
+		case err != nil:
 			return nil, fmt.Errorf("added file: %w", err)
 		}
 
@@ -119,26 +244,33 @@ func getDiffFolders(store *bds, driveId string, diff *sqlite.Difference) (*Paren
 	// changed files
 	for _, file := range diff.ChangedFiles {
 		// current
-		currentFolder, err := getFolder(store, driveId, file.New.Parent, folderMaps.Current)
-		if err != nil {
+		currentFolder, err := getFolder(store, driveId, myDrive, file.New.Parent, folderMaps.Current)
+		switch {
+		case errors.Is(err, errOutsideDrive):
+		case err != nil:
 			return nil, fmt.Errorf("changed new file: %w", err)
+		default:
+			newParents[currentFolder.ID] = *currentFolder
 		}
 
-		newParents[currentFolder.ID] = *currentFolder
-
 		// old
-		oldFolder, err := getFolder(store, driveId, file.Old.Parent, folderMaps.Old)
-		if err != nil {
+		oldFolder, err := getFolder(store, driveId, myDrive, file.Old.Parent, folderMaps.Old)
+		switch {
+		case errors.Is(err, errOutsideDrive):
+		case err != nil:
 			return nil, fmt.Errorf("changed old file: %w", err)
+		default:
+			oldParents[oldFolder.ID] = *oldFolder
 		}
-
-		oldParents[oldFolder.ID] = *oldFolder
 	}
 
 	// removed files
 	for _, file := range diff.RemovedFiles {
-		oldFolder, err := getFolder(store, driveId, file.Parent, folderMaps.Old)
-		if err != nil {
+		oldFolder, err := getFolder(store, driveId, myDrive, file.Parent, folderMaps.Old)
+		switch {
+		case errors.Is(err, errOutsideDrive):
+			continue
+		case err != nil:
 			return nil, fmt.Errorf("removed file: %w", err)
 		}
 
@@ -163,7 +295,9 @@ func getDiffFolders(store *bds, driveId string, diff *sqlite.Difference) (*Paren
 	return p, nil
 }
 
-func getFolder(store *bds, driveId string, folderId string, folderMap map[string]datastore.Folder) (*datastore.Folder, error) {
+// getFolder returns a folder of the drive.
+// For a My Drive, an errOutsideDrive is returned when the folder is not part of the drive.
+func getFolder(store *bds, driveId string, myDrive bool, folderId string, folderMap map[string]datastore.Folder) (*datastore.Folder, error) {
 	// find folder in map
 	if folder, ok := folderMap[folderId]; ok {
 		return &folder, nil
@@ -181,9 +315,16 @@ func getFolder(store *bds, driveId string, folderId string, folderMap map[string
 		return &folder, nil
 	}
 
+	if folderId == "" && myDrive {
+		return nil, errOutsideDrive
+	}
+
 	// search datastore
 	folder, err := store.GetFolder(driveId, folderId)
-	if err != nil {
+	switch {
+	case errors.Is(err, sql.ErrNoRows) && myDrive:
+		return nil, fmt.Errorf("%v: %w", folderId, errOutsideDrive)
+	case err != nil:
 		return nil, fmt.Errorf("could not get folder: %v: %w", folderId, err)
 	}
 
@@ -193,7 +334,9 @@ func getFolder(store *bds, driveId string, folderId string, folderMap map[string
 	return folder, nil
 }
 
-func getFolderPath(store *bds, driveId string, folderId string, folderMap map[string]datastore.Folder) (string, error) {
+// getFolderPath builds the path of a folder relative to the root of the drive.
+// For a My Drive, an errOutsideDrive is returned when the parents of the folder do not terminate at the drive.
+func getFolderPath(store *bds, driveId string, myDrive bool, folderId string, folderMap map[string]datastore.Folder) (string, error) {
 	path := ""
 
 	// folderId == driveId
@@ -205,7 +348,10 @@ func getFolderPath(store *bds, driveId string, folderId string, folderMap map[st
 	topFolder, ok := folderMap[folderId]
 	if !ok {
 		f, err := store.GetFolder(driveId, folderId)
-		if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows) && myDrive:
+			return filepath.Join("/", path), fmt.Errorf("%v: %w", folderId, errOutsideDrive)
+		case err != nil:
 			return filepath.Join("/", path), fmt.Errorf("could not get folder %v: %w", folderId, err)
 		}
 
@@ -221,7 +367,10 @@ func getFolderPath(store *bds, driveId string, folderId string, folderMap map[st
 		f, ok := folderMap[nextFolderId]
 		if !ok {
 			df, err := store.GetFolder(driveId, nextFolderId)
-			if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows) && myDrive:
+				return filepath.Join("/", path), fmt.Errorf("%v: %w", nextFolderId, errOutsideDrive)
+			case err != nil:
 				return filepath.Join("/", path), fmt.Errorf("could not get folder %v: %w", nextFolderId, err)
 			}
 
@@ -233,5 +382,10 @@ func getFolderPath(store *bds, driveId string, folderId string, folderMap map[st
 		nextFolderId = f.Parent
 	}
 
+	// the parents ended before reaching the drive
+	if myDrive && nextFolderId != driveId {
+		return filepath.Join("/", path), fmt.Errorf("%v: %w", folderId, errOutsideDrive)
+	}
+
 	return filepath.Join("/", path), nil
 }
@@ -0,0 +1,227 @@
+package bernard
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/l3uddz/bernard/datastore"
+)
+
+func TestRollUpCollapse(t *testing.T) {
+	type Test struct {
+		Name     string
+		RollUp   RollUp
+		Paths    []string
+		Expected []string
+	}
+
+	var testCases = []Test{
+		{
+			Name:   "Depth below the drive root",
+			RollUp: RollUp{Depth: 2},
+			Paths: []string{
+				"/TV",
+				"/TV/Show",
+				"/TV/Show/Season 1",
+				"/TV/Show/Season 2",
+				"/TV/Other/Season 1",
+			},
+			Expected: []string{
+				"/TV",
+				"/TV/Other",
+				"/TV/Show",
+			},
+		},
+		{
+			Name:   "Depth below a library root",
+			RollUp: RollUp{Depth: 1, Libraries: []string{"/Media/TV"}},
+			Paths: []string{
+				"/Media",
+				"/Media/TV",
+				"/Media/TV/Show",
+				"/Media/TV/Show/Season 1",
+				"/Media/TV/Show/Season 2",
+				"/Media/TV/Other",
+				"/Movies/Movie",
+				"/Movies/Movie/Extras",
+			},
+			Expected: []string{
+				"/Media",
+				"/Media/TV",
+				"/Media/TV/Other",
+				"/Media/TV/Show",
+				"/Movies/Movie",
+			},
+		},
+		{
+			Name:   "Changes within a folder above the depth",
+			RollUp: RollUp{Depth: 2},
+			Paths: []string{
+				"/TV",
+				"/Movies/Movie/Extras",
+			},
+			Expected: []string{
+				"/Movies/Movie/Extras",
+				"/TV",
+			},
+		},
+		{
+			Name:   "Drive root",
+			RollUp: RollUp{Depth: 2, Libraries: []string{"Media/TV"}},
+			Paths: []string{
+				"/",
+				"/Media",
+				"/Media/TV",
+				"/Media/TV/Show/Season 1",
+				"/Media/TV/Show/Season 1/Extras",
+			},
+			Expected: []string{
+				"/",
+				"/Media",
+				"/Media/TV",
+				"/Media/TV/Show/Season 1",
+			},
+		},
+		{
+			Name:   "Direct and nested changes of a folder above the depth",
+			RollUp: RollUp{Depth: 2, Libraries: []string{"/Media/TV"}},
+			Paths: []string{
+				"/Media/TV",
+				"/Media/TV/Show/Season 1",
+				"/Media/TV/Show/Season 2",
+				"/Media/TV/Other/Season 1/Extras",
+			},
+			Expected: []string{
+				"/Media/TV",
+				"/Media/TV/Other/Season 1",
+				"/Media/TV/Show/Season 1",
+				"/Media/TV/Show/Season 2",
+			},
+		},
+		{
+			Name:   "Duplicates",
+			RollUp: RollUp{Depth: 3},
+			Paths: []string{
+				"/TV/Show",
+				"/TV/Show",
+			},
+			Expected: []string{
+				"/TV/Show",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			given := append([]string{}, tc.Paths...)
+
+			result := tc.RollUp.Collapse(tc.Paths)
+			if !reflect.DeepEqual(tc.Paths, given) {
+				t.Errorf("Given paths were modified: %v", tc.Paths)
+			}
+
+			if !reflect.DeepEqual(result, tc.Expected) {
+				t.Logf("want: %v", tc.Expected)
+				t.Logf("got:  %v", result)
+				t.Errorf("Paths do not equal")
+			}
+		})
+	}
+}
+
+func TestGetFolderPath(t *testing.T) {
+	type Test struct {
+		Name     string
+		MyDrive  bool
+		Folder   string
+		Expected string
+		Outside  bool
+		Err      bool
+	}
+
+	var testCases = []Test{
+		{
+			Name:     "Folder within the drive",
+			Folder:   "show",
+			Expected: "/TV/Show",
+		},
+		{
+			Name:     "Folder within a My Drive",
+			MyDrive:  true,
+			Folder:   "show",
+			Expected: "/TV/Show",
+		},
+		{
+			Name:    "Missing parent in a My Drive",
+			MyDrive: true,
+			Folder:  "shared",
+			Outside: true,
+		},
+		{
+			Name:   "Missing parent in a shared drive",
+			Folder: "shared",
+			Err:    true,
+		},
+		{
+			Name:    "Parents ending outside of a My Drive",
+			MyDrive: true,
+			Folder:  "orphan",
+			Outside: true,
+		},
+		{
+			Name:     "Parents ending outside of a shared drive",
+			Folder:   "orphan",
+			Expected: "/Orphan",
+		},
+	}
+
+	store := getDatastore(t)
+	if _, err := store.DB.Exec(`INSERT INTO folder VALUES
+		('tv', 'a', 'TV', false, 'a'),
+		('show', 'a', 'Show', false, 'tv'),
+		('shared', 'a', 'Shared', false, 'gone'),
+		('orphan', 'a', 'Orphan', false, '')`); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			p, err := getFolderPath(store, "a", tc.MyDrive, tc.Folder, make(map[string]datastore.Folder))
+			switch {
+			case tc.Outside:
+				if !errors.Is(err, errOutsideDrive) {
+					t.Errorf("Expected folder outside of drive, got: %v %v", p, err)
+				}
+				return
+			case tc.Err:
+				if err == nil || errors.Is(err, errOutsideDrive) {
+					t.Errorf("Expected an error, got: %v %v", p, err)
+				}
+				return
+			case err != nil:
+				t.Fatal(err)
+			}
+
+			if p != tc.Expected {
+				t.Errorf("Paths do not equal: %v, expected %v", p, tc.Expected)
+			}
+		})
+	}
+}
+
+func TestGetFolder(t *testing.T) {
+	store := getDatastore(t)
+
+	// items of a My Drive without a parent are outside of the drive
+	_, err := getFolder(store, "a", true, "", make(map[string]datastore.Folder))
+	if !errors.Is(err, errOutsideDrive) {
+		t.Errorf("Expected folder outside of drive, got: %v", err)
+	}
+
+	// within shared drives every item has a parent
+	_, err = getFolder(store, "a", false, "", make(map[string]datastore.Folder))
+	if err == nil || errors.Is(err, errOutsideDrive) {
+		t.Errorf("Expected an error, got: %v", err)
+	}
+}
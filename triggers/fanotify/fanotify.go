@@ -0,0 +1,37 @@
+package fanotify
+
+import (
+	"github.com/cloudbox/autoscan"
+	"github.com/cloudbox/autoscan/triggers/inotify"
+)
+
+// Config is identical to the inotify configuration,
+// as the inotify trigger is used when fanotify is unavailable.
+type Config struct {
+	inotify.Config `yaml:",inline"`
+}
+
+// New creates an autoscan-compatible Trigger which monitors the file systems of the configured paths
+// through a single fanotify file system mark. When fanotify is not supported by the kernel or
+// autoscan lacks the CAP_SYS_ADMIN and CAP_DAC_READ_SEARCH capabilities, the inotify trigger is used instead.
+func New(c Config) (autoscan.Trigger, error) {
+	l := autoscan.GetLogger(c.Verbosity).With().
+		Str("trigger", "fanotify").
+		Logger()
+
+	paths, err := inotify.NewPaths(c.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	trigger, err := newTrigger(c, paths, l)
+	if err == nil {
+		return trigger, nil
+	}
+
+	l.Warn().
+		Err(err).
+		Msg("Fanotify is unavailable, falling back to inotify")
+
+	return inotify.New(c.Config)
+}
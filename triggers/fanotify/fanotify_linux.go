@@ -0,0 +1,267 @@
+//go:build linux
+
+package fanotify
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"unsafe"
+
+	"github.com/rs/zerolog"
+	"golang.org/x/sys/unix"
+
+	"github.com/cloudbox/autoscan"
+	"github.com/cloudbox/autoscan/triggers/inotify"
+)
+
+const (
+	initFlags = unix.FAN_CLASS_NOTIF | unix.FAN_CLOEXEC | unix.FAN_REPORT_DIR_FID | unix.FAN_REPORT_NAME
+	markFlags = unix.FAN_MARK_ADD | unix.FAN_MARK_FILESYSTEM
+	// like the inotify trigger, writes to existing files are ignored
+	markMask = unix.FAN_CREATE | unix.FAN_DELETE | unix.FAN_MOVED_FROM | unix.FAN_MOVED_TO | unix.FAN_ONDIR
+)
+
+type daemon struct {
+	fd     int
+	mounts map[unix.Fsid]int
+	paths  []inotify.Path
+	log    zerolog.Logger
+
+	// add queues the scan of an event, open resolves a directory file handle to its path
+	add  func(p *inotify.Path, name string, folder string, file bool)
+	open func(fsid unix.Fsid, fh unix.FileHandle) (string, error)
+}
+
+func newTrigger(c Config, paths []inotify.Path, l zerolog.Logger) (autoscan.Trigger, error) {
+	fd, err := unix.FanotifyInit(initFlags, unix.O_RDONLY|unix.O_LARGEFILE)
+	if err != nil {
+		return nil, fmt.Errorf("fanotify init: %w", err)
+	}
+
+	d := &daemon{
+		fd:     fd,
+		mounts: make(map[unix.Fsid]int),
+		paths:  paths,
+		log:    l,
+	}
+	d.open = d.openHandle
+
+	for _, p := range paths {
+		if err := d.mark(p.Path); err != nil {
+			d.close()
+			return nil, err
+		}
+	}
+
+	trigger := func(callback autoscan.ProcessorFunc) {
+		d.add = inotify.NewQueue(callback, l, c.Priority).Add
+
+		l.Info().
+			Int("filesystems", len(d.mounts)).
+			Msg("Watching file systems")
+
+		// start worker, inotify takes over when fanotify stops working
+		go func() {
+			err := d.worker()
+			l.Error().
+				Err(err).
+				Msg("Failed receiving filesystem events, falling back to inotify")
+
+			fallback, err := inotify.New(c.Config)
+			if err != nil {
+				l.Error().
+					Err(err).
+					Msg("Failed initialising inotify, stopped monitoring")
+				return
+			}
+
+			fallback(callback)
+		}()
+	}
+
+	return trigger, nil
+}
+
+// mark adds a mark for the file system of a path,
+// and keeps a descriptor of the file system around to resolve file handles.
+func (d *daemon) mark(path string) error {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return fmt.Errorf("statfs: %v: %w", path, err)
+	}
+
+	if _, ok := d.mounts[st.Fsid]; !ok {
+		mfd, err := unix.Open(path, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+		if err != nil {
+			return fmt.Errorf("open: %v: %w", path, err)
+		}
+
+		d.mounts[st.Fsid] = mfd
+	}
+
+	if err := unix.FanotifyMark(d.fd, markFlags, markMask, unix.AT_FDCWD, path); err != nil {
+		return fmt.Errorf("fanotify mark: %v: %w", path, err)
+	}
+
+	d.log.Debug().
+		Str("path", path).
+		Msg("Watching file system")
+
+	return nil
+}
+
+func (d *daemon) close() {
+	for _, mfd := range d.mounts {
+		_ = unix.Close(mfd)
+	}
+
+	_ = unix.Close(d.fd)
+}
+
+// worker processes the events until reading them fails.
+func (d *daemon) worker() error {
+	defer d.close()
+
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := unix.Read(d.fd, buf)
+		switch {
+		case errors.Is(err, unix.EINTR):
+			continue
+		case err != nil:
+			return fmt.Errorf("read: %w", err)
+		}
+
+		const metaSize = int(unsafe.Sizeof(unix.FanotifyEventMetadata{}))
+		for offset := 0; offset+metaSize <= n; {
+			meta := (*unix.FanotifyEventMetadata)(unsafe.Pointer(&buf[offset]))
+			if meta.Vers != unix.FANOTIFY_METADATA_VERSION {
+				return fmt.Errorf("unsupported fanotify metadata version: %d", meta.Vers)
+			}
+
+			end := offset + int(meta.Event_len)
+			if end > n || int(meta.Event_len) < metaSize {
+				break
+			}
+
+			d.handleEvent(meta.Mask, buf[offset+int(meta.Metadata_len):end])
+			offset = end
+		}
+	}
+}
+
+func (d *daemon) handleEvent(mask uint64, info []byte) {
+	dir, name, err := d.resolve(info)
+	if err != nil {
+		d.log.Debug().
+			Err(err).
+			Uint64("mask", mask).
+			Msg("Failed resolving event path")
+		return
+	}
+
+	full := dir
+	if name != "" && name != "." {
+		full = filepath.Join(dir, name)
+	}
+
+	// file system marks report events outside of the configured paths as well
+	p, err := inotify.MatchPath(d.paths, full)
+	if err != nil {
+		return
+	}
+
+	d.log.Trace().
+		Str("path", full).
+		Uint64("mask", mask).
+		Msg("Filesystem event")
+
+	created := mask&(unix.FAN_CREATE|unix.FAN_MOVED_TO) != 0
+
+	switch {
+	case mask&unix.FAN_ONDIR != 0 && created:
+		// new directory, may already contain files when moved from elsewhere
+		d.add(p, full, full, false)
+	case mask&unix.FAN_ONDIR != 0:
+		// removed directory, scan the parent folder
+		d.add(p, full, dir, false)
+	default:
+		d.add(p, full, dir, created)
+	}
+}
+
+// resolve returns the directory and entry name of the first directory record of an event.
+func (d *daemon) resolve(info []byte) (string, string, error) {
+	const hdrSize = 4
+	const fsidSize = int(unsafe.Sizeof(unix.Fsid{}))
+	const handleHdrSize = 8
+
+	for len(info) >= hdrSize {
+		infoType := info[0]
+		infoLen := int(*(*uint16)(unsafe.Pointer(&info[2])))
+		if infoLen < hdrSize || infoLen > len(info) {
+			return "", "", errors.New("malformed event info")
+		}
+
+		record := info[:infoLen]
+		info = info[infoLen:]
+
+		switch infoType {
+		case unix.FAN_EVENT_INFO_TYPE_DFID_NAME, unix.FAN_EVENT_INFO_TYPE_DFID,
+			unix.FAN_EVENT_INFO_TYPE_OLD_DFID_NAME, unix.FAN_EVENT_INFO_TYPE_NEW_DFID_NAME:
+		default:
+			continue
+		}
+
+		if len(record) < hdrSize+fsidSize+handleHdrSize {
+			return "", "", errors.New("malformed file handle")
+		}
+
+		fsid := *(*unix.Fsid)(unsafe.Pointer(&record[hdrSize]))
+		handle := record[hdrSize+fsidSize:]
+		handleBytes := int(*(*uint32)(unsafe.Pointer(&handle[0])))
+		handleType := *(*int32)(unsafe.Pointer(&handle[4]))
+		if handleHdrSize+handleBytes > len(handle) {
+			return "", "", errors.New("malformed file handle")
+		}
+
+		// the entry name follows the file handle
+		name := ""
+		if infoType != unix.FAN_EVENT_INFO_TYPE_DFID {
+			raw := handle[handleHdrSize+handleBytes:]
+			if i := bytes.IndexByte(raw, 0); i >= 0 {
+				raw = raw[:i]
+			}
+			name = string(raw)
+		}
+
+		dir, err := d.open(fsid, unix.NewFileHandle(handleType, handle[handleHdrSize:handleHdrSize+handleBytes]))
+		if err != nil {
+			return "", "", err
+		}
+
+		return dir, name, nil
+	}
+
+	return "", "", errors.New("no directory record in event")
+}
+
+// openHandle resolves a directory file handle to its current path.
+func (d *daemon) openHandle(fsid unix.Fsid, fh unix.FileHandle) (string, error) {
+	mfd, ok := d.mounts[fsid]
+	if !ok {
+		return "", errors.New("unknown file system")
+	}
+
+	fd, err := unix.OpenByHandleAt(mfd, fh, unix.O_PATH)
+	if err != nil {
+		// the directory might have been removed since
+		return "", fmt.Errorf("open by handle: %w", err)
+	}
+	defer unix.Close(fd)
+
+	return os.Readlink(fmt.Sprintf("/proc/self/fd/%d", fd))
+}
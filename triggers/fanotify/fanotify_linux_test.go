@@ -0,0 +1,270 @@
+//go:build linux
+
+package fanotify
+
+import (
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"golang.org/x/sys/unix"
+
+	"github.com/cloudbox/autoscan"
+	"github.com/cloudbox/autoscan/triggers/inotify"
+)
+
+var testFsid = unix.Fsid{Val: [2]int32{1, 2}}
+
+// record encodes an event info record with a file handle, followed by the entry name.
+func record(infoType byte, fsid unix.Fsid, handle string, name string) []byte {
+	b := []byte{infoType, 0, 0, 0}
+	b = binary.LittleEndian.AppendUint32(b, uint32(fsid.Val[0]))
+	b = binary.LittleEndian.AppendUint32(b, uint32(fsid.Val[1]))
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(handle)))
+	b = binary.LittleEndian.AppendUint32(b, 1)
+	b = append(b, handle...)
+
+	if name != "" {
+		b = append(b, name...)
+		b = append(b, 0)
+	}
+
+	binary.LittleEndian.PutUint16(b[2:], uint16(len(b)))
+	return b
+}
+
+// newTestDaemon creates a daemon which resolves the file handles of testFsid from the given directories.
+func newTestDaemon(t *testing.T, dirs map[string]string) *daemon {
+	rewriter, err := autoscan.NewRewriter(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filterer, err := autoscan.NewFilterer(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &daemon{
+		log: zerolog.Nop(),
+		paths: []inotify.Path{{
+			Path:     "/mnt/unionfs/TV",
+			Rewriter: rewriter,
+			Allowed:  filterer,
+		}},
+		open: func(fsid unix.Fsid, fh unix.FileHandle) (string, error) {
+			if fsid != testFsid {
+				return "", errors.New("unknown file system")
+			}
+
+			dir, ok := dirs[string(fh.Bytes())]
+			if !ok {
+				return "", errors.New("stale file handle")
+			}
+
+			return dir, nil
+		},
+	}
+}
+
+func TestResolve(t *testing.T) {
+	type Test struct {
+		Name         string
+		Info         []byte
+		ExpectedDir  string
+		ExpectedName string
+		Err          bool
+	}
+
+	var testCases = []Test{
+		{
+			Name:         "Directory with entry name",
+			Info:         record(unix.FAN_EVENT_INFO_TYPE_DFID_NAME, testFsid, "show", "S01E01.mkv"),
+			ExpectedDir:  "/mnt/unionfs/TV/Show",
+			ExpectedName: "S01E01.mkv",
+		},
+		{
+			Name:         "Directory without entry name",
+			Info:         record(unix.FAN_EVENT_INFO_TYPE_DFID, testFsid, "show", ""),
+			ExpectedDir:  "/mnt/unionfs/TV/Show",
+			ExpectedName: "",
+		},
+		{
+			Name: "Skips records of files",
+			Info: append(
+				record(unix.FAN_EVENT_INFO_TYPE_FID, testFsid, "file", ""),
+				record(unix.FAN_EVENT_INFO_TYPE_DFID_NAME, testFsid, "tv", "Show")...,
+			),
+			ExpectedDir:  "/mnt/unionfs/TV",
+			ExpectedName: "Show",
+		},
+		{
+			Name:         "Old directory of a move",
+			Info:         record(unix.FAN_EVENT_INFO_TYPE_OLD_DFID_NAME, testFsid, "tv", "Show"),
+			ExpectedDir:  "/mnt/unionfs/TV",
+			ExpectedName: "Show",
+		},
+		{
+			Name: "Malformed record length",
+			Info: func() []byte {
+				b := record(unix.FAN_EVENT_INFO_TYPE_DFID_NAME, testFsid, "show", "S01E01.mkv")
+				binary.LittleEndian.PutUint16(b[2:], uint16(len(b)+1))
+				return b
+			}(),
+			Err: true,
+		},
+		{
+			Name: "Malformed file handle",
+			Info: func() []byte {
+				b := record(unix.FAN_EVENT_INFO_TYPE_DFID, testFsid, "show", "")
+				binary.LittleEndian.PutUint32(b[12:], 64)
+				return b
+			}(),
+			Err: true,
+		},
+		{
+			Name: "No directory record",
+			Info: record(unix.FAN_EVENT_INFO_TYPE_FID, testFsid, "file", ""),
+			Err:  true,
+		},
+		{
+			Name: "Unknown file system",
+			Info: record(unix.FAN_EVENT_INFO_TYPE_DFID_NAME, unix.Fsid{Val: [2]int32{3, 4}}, "show", "S01E01.mkv"),
+			Err:  true,
+		},
+	}
+
+	d := newTestDaemon(t, map[string]string{
+		"tv":   "/mnt/unionfs/TV",
+		"show": "/mnt/unionfs/TV/Show",
+	})
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			dir, name, err := d.resolve(tc.Info)
+			switch {
+			case tc.Err && err == nil:
+				t.Fatalf("Expected an error, got: %v %v", dir, name)
+			case tc.Err:
+				return
+			case err != nil:
+				t.Fatal(err)
+			}
+
+			if dir != tc.ExpectedDir || name != tc.ExpectedName {
+				t.Errorf("Resolved path does not equal: %q %q, expected %q %q", dir, name, tc.ExpectedDir, tc.ExpectedName)
+			}
+		})
+	}
+}
+
+func TestHandleEvent(t *testing.T) {
+	type Added struct {
+		Name   string
+		Folder string
+		File   bool
+	}
+
+	type Test struct {
+		Name     string
+		Mask     uint64
+		Info     []byte
+		Expected []Added
+	}
+
+	var testCases = []Test{
+		{
+			Name: "Created file",
+			Mask: unix.FAN_CREATE,
+			Info: record(unix.FAN_EVENT_INFO_TYPE_DFID_NAME, testFsid, "show", "S01E01.mkv"),
+			Expected: []Added{
+				{Name: "/mnt/unionfs/TV/Show/S01E01.mkv", Folder: "/mnt/unionfs/TV/Show", File: true},
+			},
+		},
+		{
+			Name: "File moved in",
+			Mask: unix.FAN_MOVED_TO,
+			Info: record(unix.FAN_EVENT_INFO_TYPE_DFID_NAME, testFsid, "show", "S01E01.mkv"),
+			Expected: []Added{
+				{Name: "/mnt/unionfs/TV/Show/S01E01.mkv", Folder: "/mnt/unionfs/TV/Show", File: true},
+			},
+		},
+		{
+			Name: "Deleted file",
+			Mask: unix.FAN_DELETE,
+			Info: record(unix.FAN_EVENT_INFO_TYPE_DFID_NAME, testFsid, "show", "S01E01.mkv"),
+			Expected: []Added{
+				{Name: "/mnt/unionfs/TV/Show/S01E01.mkv", Folder: "/mnt/unionfs/TV/Show", File: false},
+			},
+		},
+		{
+			Name: "Created directory",
+			Mask: unix.FAN_CREATE | unix.FAN_ONDIR,
+			Info: record(unix.FAN_EVENT_INFO_TYPE_DFID_NAME, testFsid, "tv", "Show"),
+			Expected: []Added{
+				{Name: "/mnt/unionfs/TV/Show", Folder: "/mnt/unionfs/TV/Show", File: false},
+			},
+		},
+		{
+			Name: "Directory moved in",
+			Mask: unix.FAN_MOVED_TO | unix.FAN_ONDIR,
+			Info: record(unix.FAN_EVENT_INFO_TYPE_DFID_NAME, testFsid, "tv", "Show"),
+			Expected: []Added{
+				{Name: "/mnt/unionfs/TV/Show", Folder: "/mnt/unionfs/TV/Show", File: false},
+			},
+		},
+		{
+			Name: "Deleted directory",
+			Mask: unix.FAN_DELETE | unix.FAN_ONDIR,
+			Info: record(unix.FAN_EVENT_INFO_TYPE_DFID_NAME, testFsid, "tv", "Show"),
+			Expected: []Added{
+				{Name: "/mnt/unionfs/TV/Show", Folder: "/mnt/unionfs/TV", File: false},
+			},
+		},
+		{
+			Name: "Directory moved out",
+			Mask: unix.FAN_MOVED_FROM | unix.FAN_ONDIR,
+			Info: record(unix.FAN_EVENT_INFO_TYPE_DFID_NAME, testFsid, "tv", "Show"),
+			Expected: []Added{
+				{Name: "/mnt/unionfs/TV/Show", Folder: "/mnt/unionfs/TV", File: false},
+			},
+		},
+		{
+			Name:     "Outside of the configured paths",
+			Mask:     unix.FAN_CREATE,
+			Info:     record(unix.FAN_EVENT_INFO_TYPE_DFID_NAME, testFsid, "movies", "Interstellar.mkv"),
+			Expected: nil,
+		},
+		{
+			Name:     "Unresolvable directory",
+			Mask:     unix.FAN_CREATE,
+			Info:     record(unix.FAN_EVENT_INFO_TYPE_DFID_NAME, testFsid, "removed", "S01E01.mkv"),
+			Expected: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			d := newTestDaemon(t, map[string]string{
+				"tv":     "/mnt/unionfs/TV",
+				"show":   "/mnt/unionfs/TV/Show",
+				"movies": "/mnt/unionfs/Movies",
+			})
+
+			var added []Added
+			d.add = func(p *inotify.Path, name string, folder string, file bool) {
+				added = append(added, Added{Name: name, Folder: folder, File: file})
+			}
+
+			d.handleEvent(tc.Mask, tc.Info)
+
+			if !reflect.DeepEqual(added, tc.Expected) {
+				t.Logf("want: %v", tc.Expected)
+				t.Logf("got:  %v", added)
+				t.Errorf("Queued events do not equal")
+			}
+		})
+	}
+}
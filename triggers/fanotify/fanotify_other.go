@@ -0,0 +1,16 @@
+//go:build !linux
+
+package fanotify
+
+import (
+	"errors"
+
+	"github.com/rs/zerolog"
+
+	"github.com/cloudbox/autoscan"
+	"github.com/cloudbox/autoscan/triggers/inotify"
+)
+
+func newTrigger(Config, []inotify.Path, zerolog.Logger) (autoscan.Trigger, error) {
+	return nil, errors.New("fanotify is only supported on linux")
+}
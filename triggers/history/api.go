@@ -0,0 +1,125 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/cloudbox/autoscan"
+)
+
+type apiClient struct {
+	client  *http.Client
+	baseURL string
+	apiKey  string
+	include string
+}
+
+func newAPIClient(baseURL string, apiKey string, include string) *apiClient {
+	return &apiClient{
+		client:  &http.Client{Timeout: time.Minute},
+		baseURL: baseURL,
+		apiKey:  apiKey,
+		include: include,
+	}
+}
+
+// A record is a single event in the history of Sonarr or Radarr.
+type record struct {
+	ID          int64             `json:"id"`
+	Type        string            `json:"eventType"`
+	Date        time.Time         `json:"date"`
+	SourceTitle string            `json:"sourceTitle"`
+	Data        map[string]string `json:"data"`
+
+	// Series is only included by Sonarr, Movie only by Radarr.
+	Series struct {
+		Path string `json:"path"`
+	} `json:"series"`
+	Movie struct {
+		Path string `json:"path"`
+	} `json:"movie"`
+}
+
+// value returns a value of the event data, ignoring the case of the key.
+func (r record) value(key string) string {
+	for k, v := range r.Data {
+		if strings.EqualFold(k, key) {
+			return v
+		}
+	}
+
+	return ""
+}
+
+func (c apiClient) get(path string, query url.Values, v interface{}) error {
+	reqURL := autoscan.JoinURL(c.baseURL, "api", "v3", path) + "?" + query.Encode()
+	req, err := http.NewRequest("GET", reqURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed creating request: %w", err)
+	}
+
+	req.Header.Set("X-Api-Key", c.apiKey)
+	req.Header.Set("Accept", "application/json")
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode == 401:
+		return fmt.Errorf("invalid api key: %s", res.Status)
+	case res.StatusCode < 200 || res.StatusCode >= 300:
+		return fmt.Errorf("%s", res.Status)
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed decoding response: %w", err)
+	}
+
+	return nil
+}
+
+// Latest returns the most recent history record, or nil when the history is empty.
+func (c apiClient) Latest() (*record, error) {
+	query := url.Values{}
+	query.Set("page", "1")
+	query.Set("pageSize", "1")
+	query.Set("sortKey", "date")
+	query.Set("sortDirection", "descending")
+
+	type Response struct {
+		Records []record `json:"records"`
+	}
+
+	resp := new(Response)
+	if err := c.get("history", query, resp); err != nil {
+		return nil, fmt.Errorf("history: %w", err)
+	}
+
+	if len(resp.Records) == 0 {
+		return nil, nil
+	}
+
+	return &resp.Records[0], nil
+}
+
+// Since returns the history records since the given date, including the series or movie.
+func (c apiClient) Since(date time.Time) ([]record, error) {
+	query := url.Values{}
+	query.Set("date", date.UTC().Format(time.RFC3339))
+	query.Set(c.include, "true")
+
+	records := make([]record, 0)
+	if err := c.get("history/since", query, &records); err != nil {
+		return nil, fmt.Errorf("history since: %w", err)
+	}
+
+	return records, nil
+}
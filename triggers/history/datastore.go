@@ -0,0 +1,67 @@
+package history
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cloudbox/autoscan/migrate"
+)
+
+type datastore struct {
+	*sql.DB
+}
+
+var (
+	//go:embed migrations
+	migrations embed.FS
+)
+
+func newDatastore(db *sql.DB, mg *migrate.Migrator) (*datastore, error) {
+	// migrations
+	if err := mg.Migrate(&migrations, "history"); err != nil {
+		return nil, fmt.Errorf("migrate: %w", err)
+	}
+
+	return &datastore{db}, nil
+}
+
+// A Cursor is the last history record which was processed.
+type Cursor struct {
+	ID   int64
+	Date time.Time
+}
+
+const sqlSelectCursor = `SELECT id, date FROM history_cursor WHERE name = ?`
+
+// Cursor returns the cursor of a trigger, or false when the trigger has no cursor yet.
+func (store *datastore) Cursor(name string) (Cursor, bool, error) {
+	row := store.QueryRow(sqlSelectCursor, name)
+
+	c := Cursor{}
+	err := row.Scan(&c.ID, &c.Date)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return c, false, nil
+	case err != nil:
+		return c, false, err
+	}
+
+	return c, true, nil
+}
+
+const sqlUpsertCursor = `
+INSERT INTO history_cursor (name, id, date)
+VALUES (?, ?, ?)
+ON CONFLICT (name) DO UPDATE SET
+	id = excluded.id,
+	date = excluded.date
+`
+
+// SetCursor stores the cursor of a trigger.
+func (store *datastore) SetCursor(name string, c Cursor) error {
+	_, err := store.Exec(sqlUpsertCursor, name, c.ID, c.Date)
+	return err
+}
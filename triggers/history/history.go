@@ -0,0 +1,288 @@
+package history
+
+import (
+	"database/sql"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/cloudbox/autoscan"
+	"github.com/cloudbox/autoscan/migrate"
+)
+
+const (
+	defaultInterval = 5 * time.Minute
+
+	// tolerated difference between the clocks of autoscan and the -arr
+	receivedWindow = time.Minute
+)
+
+type Config struct {
+	Name      string             `yaml:"name"`
+	Type      string             `yaml:"type"`
+	URL       string             `yaml:"url"`
+	APIKey    string             `yaml:"api-key"`
+	Interval  time.Duration      `yaml:"interval"`
+	Priority  int                `yaml:"priority"`
+	Verbosity string             `yaml:"verbosity"`
+	Rewrite   []autoscan.Rewrite `yaml:"rewrite"`
+}
+
+// A ReceivedFunc returns whether a scan of the folder was received since the given time.
+type ReceivedFunc func(folder string, since time.Time) (bool, error)
+
+type daemon struct {
+	name     string
+	callback autoscan.ProcessorFunc
+	received ReceivedFunc
+	api      *apiClient
+	store    *datastore
+	priority int
+	interval time.Duration
+	rewrite  autoscan.Rewriter
+	log      zerolog.Logger
+}
+
+// New creates an autoscan-compatible Trigger which polls the history of Sonarr or Radarr,
+// to scan the imports, deletions and renames of which the webhook was missed.
+// Folders for which a scan was received after the event are skipped.
+func New(c Config, db *sql.DB, mg *migrate.Migrator, received ReceivedFunc) (autoscan.Trigger, error) {
+	name := c.Name
+	if name == "" {
+		name = c.Type
+	}
+
+	l := autoscan.GetLogger(c.Verbosity).With().
+		Str("trigger", "history").
+		Str("name", name).
+		Logger()
+
+	var include string
+	switch strings.ToLower(c.Type) {
+	case "sonarr":
+		include = "includeSeries"
+	case "radarr":
+		include = "includeMovie"
+	default:
+		return nil, fmt.Errorf("unsupported type %q, expected sonarr or radarr: %w", c.Type, autoscan.ErrFatal)
+	}
+
+	if c.URL == "" || c.APIKey == "" {
+		return nil, fmt.Errorf("url and api key are required: %w", autoscan.ErrFatal)
+	}
+
+	rewriter, err := autoscan.NewRewriter(c.Rewrite)
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := newDatastore(db, mg)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", err, autoscan.ErrFatal)
+	}
+
+	interval := c.Interval
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+
+	trigger := func(callback autoscan.ProcessorFunc) {
+		d := daemon{
+			name:     name,
+			callback: callback,
+			received: received,
+			api:      newAPIClient(c.URL, c.APIKey, include),
+			store:    store,
+			priority: c.Priority,
+			interval: interval,
+			rewrite:  rewriter,
+			log:      l,
+		}
+
+		d.worker()
+	}
+
+	return trigger, nil
+}
+
+func (d *daemon) worker() {
+	t := time.NewTicker(d.interval)
+	defer t.Stop()
+
+	for {
+		if err := d.poll(); err != nil {
+			d.log.Error().
+				Err(err).
+				Msg("Failed polling history")
+		}
+
+		<-t.C
+	}
+}
+
+// poll processes the history records since the cursor, and moves the cursor once the scans were processed.
+func (d *daemon) poll() error {
+	cursor, ok, err := d.store.Cursor(d.name)
+	if err != nil {
+		return fmt.Errorf("cursor: %w", err)
+	}
+
+	// start at the most recent record, the webhooks of earlier records are not known to be missed
+	if !ok {
+		latest, err := d.api.Latest()
+		if err != nil {
+			return err
+		}
+
+		if latest != nil {
+			cursor = Cursor{ID: latest.ID, Date: latest.Date}
+		}
+
+		if err := d.store.SetCursor(d.name, cursor); err != nil {
+			return fmt.Errorf("set cursor: %w", err)
+		}
+
+		d.log.Info().
+			Int64("id", cursor.ID).
+			Msg("Initialised history cursor")
+		return nil
+	}
+
+	records, err := d.api.Since(cursor.Date)
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].ID < records[j].ID
+	})
+
+	scans := make([]autoscan.Scan, 0)
+	unique := make(map[string]bool)
+	next := cursor
+
+	for _, r := range records {
+		// records of the cursor date might have been processed already
+		if r.ID <= cursor.ID {
+			continue
+		}
+
+		next = Cursor{ID: r.ID, Date: r.Date}
+
+		for _, p := range paths(r) {
+			folderPath := d.rewrite(p)
+			if unique[folderPath] {
+				continue
+			}
+
+			unique[folderPath] = true
+
+			// the stored scans of the webhooks are rewritten by the -arr triggers,
+			// so this only matches when the rewrite rules of both triggers result in the same folder
+			received, err := d.received(folderPath, r.Date.Add(-receivedWindow))
+			if err != nil {
+				return fmt.Errorf("received: %w", err)
+			}
+
+			if received {
+				d.log.Debug().
+					Str("path", folderPath).
+					Str("event", r.Type).
+					Msg("Scan already received")
+				continue
+			}
+
+			scans = append(scans, autoscan.Scan{
+				Folder:   folderPath,
+				Priority: d.priority,
+				Time:     now(),
+			})
+		}
+	}
+
+	if len(scans) > 0 {
+		if err := d.callback(scans...); err != nil {
+			return fmt.Errorf("processor: %w", err)
+		}
+
+		for _, scan := range scans {
+			d.log.Info().
+				Str("path", scan.Folder).
+				Msg("Scan moved to processor")
+		}
+	}
+
+	if next == cursor {
+		return nil
+	}
+
+	if err := d.store.SetCursor(d.name, next); err != nil {
+		return fmt.Errorf("set cursor: %w", err)
+	}
+
+	d.log.Debug().
+		Int64("id", next.ID).
+		Int("records", len(records)).
+		Msg("Moved history cursor")
+	return nil
+}
+
+// paths returns the folders to scan for a history record,
+// using the same folders as the webhooks of the event.
+func paths(r record) []string {
+	parent := r.Series.Path
+	if parent == "" {
+		parent = r.Movie.Path
+	}
+
+	// file paths of deleted files might be relative to the series or movie folder
+	folder := func(p string) string {
+		if p == "" {
+			return ""
+		}
+
+		if !path.IsAbs(p) {
+			if parent == "" {
+				return ""
+			}
+
+			p = path.Join(parent, p)
+		}
+
+		return path.Dir(p)
+	}
+
+	var folders []string
+	switch strings.ToLower(r.Type) {
+	case "downloadfolderimported", "seriesfolderimported", "moviefolderimported":
+		f := folder(r.value("importedPath"))
+		if f == "" {
+			f = parent
+		}
+
+		folders = append(folders, f)
+	case "episodefiledeleted", "moviefiledeleted":
+		folders = append(folders, folder(r.SourceTitle))
+	case "episodefilerenamed", "moviefilerenamed":
+		folders = append(folders, folder(r.value("sourcePath")), folder(r.value("path")))
+	}
+
+	unique := make(map[string]bool)
+	result := make([]string, 0, len(folders))
+	for _, f := range folders {
+		if f == "" || unique[f] {
+			continue
+		}
+
+		unique[f] = true
+		result = append(result, f)
+	}
+
+	return result
+}
+
+var now = time.Now
@@ -0,0 +1,215 @@
+package history
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cloudbox/autoscan"
+	"github.com/cloudbox/autoscan/migrate"
+
+	// sqlite3 driver
+	_ "modernc.org/sqlite"
+)
+
+func getDatastore(t *testing.T) *datastore {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every connection opens a separate in-memory database
+	db.SetMaxOpenConns(1)
+
+	mg, err := migrate.New(db, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := newDatastore(db, mg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return store
+}
+
+func TestPaths(t *testing.T) {
+	type Test struct {
+		Name     string
+		Fixture  string
+		Expected [][]string
+	}
+
+	var testCases = []Test{
+		{
+			Name:    "Sonarr",
+			Fixture: "testdata/sonarr.json",
+			Expected: [][]string{
+				{"/TV/Westworld/Season 1"},
+				nil,
+				{"/TV/Westworld/Season 1"},
+				{"/TV/Westworld/Season 2"},
+				{"/TV/Chernobyl/Season1", "/TV/Chernobyl/Season 1"},
+				{"/TV/Chernobyl/Season 1"},
+			},
+		},
+		{
+			Name:    "Radarr",
+			Fixture: "testdata/radarr.json",
+			Expected: [][]string{
+				{"/Movies/Interstellar (2014)"},
+				{"/Movies/Parasite (2019)"},
+				{"/Movies/Tenet", "/Movies/Tenet (2020)"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			records := make([]record, 0)
+			if err := readFixture(tc.Fixture, &records); err != nil {
+				t.Fatal(err)
+			}
+
+			for i, r := range records {
+				got := paths(r)
+				if len(got) == 0 && len(tc.Expected[i]) == 0 {
+					continue
+				}
+
+				if !reflect.DeepEqual(got, tc.Expected[i]) {
+					t.Logf("want: %v", tc.Expected[i])
+					t.Logf("got:  %v", got)
+					t.Errorf("Paths of record %d do not equal", r.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestPoll(t *testing.T) {
+	fixture, err := os.ReadFile("testdata/sonarr.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Api-Key") != "key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/api/v3/history":
+			_, _ = w.Write([]byte(`{"records": [{"id": 10, "date": "2021-03-01T11:50:00Z"}]}`))
+		case "/api/v3/history/since":
+			if r.URL.Query().Get("date") != "2021-03-01T11:50:00Z" || r.URL.Query().Get("includeSeries") != "true" {
+				t.Errorf("Unexpected query: %v", r.URL.RawQuery)
+			}
+
+			_, _ = w.Write(fixture)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	currentTime := time.Now()
+	now = func() time.Time {
+		return currentTime
+	}
+	defer func() {
+		now = time.Now
+	}()
+
+	var scans []autoscan.Scan
+	var callbackErr error
+
+	rewriter, _ := autoscan.NewRewriter([]autoscan.Rewrite{{
+		From: "^/TV/",
+		To:   "/mnt/unionfs/Media/TV/",
+	}})
+
+	d := &daemon{
+		name: "sonarr",
+		callback: func(s ...autoscan.Scan) error {
+			scans = s
+			return callbackErr
+		},
+		received: func(folder string, since time.Time) (bool, error) {
+			// the webhook of the first import was received
+			return folder == "/mnt/unionfs/Media/TV/Westworld/Season 1" && since.Before(time.Date(2021, 3, 1, 12, 5, 0, 0, time.UTC)), nil
+		},
+		api:      newAPIClient(server.URL, "key", "includeSeries"),
+		store:    getDatastore(t),
+		priority: 2,
+		rewrite:  rewriter,
+	}
+
+	// the first poll starts at the most recent record
+	if err := d.poll(); err != nil {
+		t.Fatal(err)
+	}
+
+	if scans != nil {
+		t.Errorf("Expected no scans on the first poll, got: %v", scans)
+	}
+
+	// the processor fails, the cursor must not move
+	callbackErr = errors.New("processor failure")
+	if err := d.poll(); err == nil {
+		t.Fatal("Expected an error")
+	}
+
+	cursor, _, err := d.store.Cursor("sonarr")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cursor.ID != 10 {
+		t.Errorf("Expected cursor 10, got: %d", cursor.ID)
+	}
+
+	// records after the cursor are scanned
+	callbackErr = nil
+	if err := d.poll(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []autoscan.Scan{
+		{Folder: "/mnt/unionfs/Media/TV/Westworld/Season 2", Priority: 2, Time: currentTime},
+		{Folder: "/mnt/unionfs/Media/TV/Chernobyl/Season1", Priority: 2, Time: currentTime},
+		{Folder: "/mnt/unionfs/Media/TV/Chernobyl/Season 1", Priority: 2, Time: currentTime},
+	}
+
+	if !reflect.DeepEqual(scans, expected) {
+		t.Logf("want: %v", expected)
+		t.Logf("got:  %v", scans)
+		t.Errorf("Scans do not equal")
+	}
+
+	cursor, _, err = d.store.Cursor("sonarr")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cursor.ID != 15 || !cursor.Date.Equal(time.Date(2021, 3, 1, 12, 20, 0, 0, time.UTC)) {
+		t.Errorf("Expected cursor 15, got: %v", cursor)
+	}
+}
+
+func readFixture(name string, v interface{}) error {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, v)
+}
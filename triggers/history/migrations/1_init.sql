@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS history_cursor (
+    "name" TEXT NOT NULL,
+    "id" INTEGER NOT NULL,
+    "date" DATETIME NOT NULL,
+    PRIMARY KEY(name)
+)
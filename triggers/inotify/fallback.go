@@ -0,0 +1,135 @@
+package inotify
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudbox/autoscan/triggers/poll"
+)
+
+const (
+	maxUserWatchesFile  = "/proc/sys/fs/inotify/max_user_watches"
+	defaultPollInterval = 5 * time.Minute
+)
+
+// maxUserWatches returns the inotify watch limit of the kernel, or 0 when unknown.
+func maxUserWatches() int {
+	b, err := os.ReadFile(maxUserWatchesFile)
+	if err != nil {
+		return 0
+	}
+
+	limit, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil {
+		return 0
+	}
+
+	return limit
+}
+
+// fallback polls a directory tree which could not be watched.
+func (d *daemon) fallback(root string) {
+	d.pollLock.Lock()
+	defer d.pollLock.Unlock()
+
+	if _, ok := d.polled[root]; ok {
+		return
+	}
+
+	snapshot, err := poll.Walk(root, 0, 1)
+	if err != nil {
+		d.log.Error().
+			Err(err).
+			Str("path", root).
+			Msg("Failed creating snapshot of unwatched directory")
+		return
+	}
+
+	d.polled[root] = snapshot
+	d.updatePolledCount()
+
+	d.log.Warn().
+		Str("path", root).
+		Int("directories", len(snapshot)).
+		Msg("Watch limit reached, polling directory instead")
+}
+
+func (d *daemon) pollWorker() {
+	t := time.NewTicker(d.pollInterval)
+	defer t.Stop()
+
+	for range t.C {
+		d.pollFallbacks()
+	}
+}
+
+func (d *daemon) pollFallbacks() {
+	d.pollLock.Lock()
+	roots := make(map[string]poll.Snapshot, len(d.polled))
+	for root, snapshot := range d.polled {
+		roots[root] = snapshot
+	}
+	d.pollLock.Unlock()
+
+	directories := 0
+	for root, previous := range roots {
+		current, err := poll.Walk(root, 0, 1)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			// the scan was queued by the event of the watched parent
+			d.removeFallback(root)
+			continue
+		case err != nil:
+			d.log.Error().
+				Err(err).
+				Str("path", root).
+				Msg("Failed polling unwatched directory")
+			continue
+		}
+
+		d.pollLock.Lock()
+		d.polled[root] = current
+		d.updatePolledCount()
+		d.pollLock.Unlock()
+
+		directories += len(current)
+
+		changes := poll.Compare(previous, current)
+		for _, folder := range poll.RootFolders(changes.Paths()) {
+			d.queueFolder(folder, folder)
+		}
+	}
+
+	if len(roots) > 0 {
+		d.log.Debug().
+			Int("polled", len(roots)).
+			Int("directories", directories).
+			Msg("Polled unwatched directories")
+	}
+}
+
+func (d *daemon) removeFallback(root string) {
+	d.pollLock.Lock()
+	defer d.pollLock.Unlock()
+
+	delete(d.polled, root)
+	d.updatePolledCount()
+
+	d.log.Debug().
+		Str("path", root).
+		Msg("Stopped polling directory")
+}
+
+// updatePolledCount stores the amount of polled directories, the poll lock must be held.
+func (d *daemon) updatePolledCount() {
+	count := 0
+	for _, snapshot := range d.polled {
+		count += len(snapshot)
+	}
+
+	d.polledCount.Store(int64(count))
+}
@@ -1,45 +1,85 @@
 package inotify
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/rs/zerolog"
 
 	"github.com/cloudbox/autoscan"
+	"github.com/cloudbox/autoscan/triggers/poll"
 )
 
 type Config struct {
-	Priority  int                `yaml:"priority"`
-	Verbosity string             `yaml:"verbosity"`
-	Rewrite   []autoscan.Rewrite `yaml:"rewrite"`
-	Include   []string           `yaml:"include"`
-	Exclude   []string           `yaml:"exclude"`
-	Paths     []struct {
-		Path    string             `yaml:"path"`
-		Rewrite []autoscan.Rewrite `yaml:"rewrite"`
-		Include []string           `yaml:"include"`
-		Exclude []string           `yaml:"exclude"`
+	Priority     int                `yaml:"priority"`
+	Delay        time.Duration      `yaml:"delay"`
+	Stability    time.Duration      `yaml:"stability"`
+	PollInterval time.Duration      `yaml:"poll-interval"`
+	Supervise    time.Duration      `yaml:"supervise"`
+	CatchUp      bool               `yaml:"catch-up"`
+	Verbosity    string             `yaml:"verbosity"`
+	Rewrite      []autoscan.Rewrite `yaml:"rewrite"`
+	Include      []string           `yaml:"include"`
+	Exclude      []string           `yaml:"exclude"`
+	Paths        []struct {
+		Path      string             `yaml:"path"`
+		Delay     time.Duration      `yaml:"delay"`
+		Stability time.Duration      `yaml:"stability"`
+		Rewrite   []autoscan.Rewrite `yaml:"rewrite"`
+		Include   []string           `yaml:"include"`
+		Exclude   []string           `yaml:"exclude"`
 	} `yaml:"paths"`
 }
 
+const (
+	// maximum time between the rename and create events of a move
+	renameWindow = time.Second
+
+	// time to wait for further events before a folder is scanned
+	defaultDelay = 10 * time.Second
+)
+
 type daemon struct {
-	callback autoscan.ProcessorFunc
-	paths    []path
-	watcher  *fsnotify.Watcher
-	queue    *queue
-	log      zerolog.Logger
+	callback     autoscan.ProcessorFunc
+	paths        []Path
+	watcher      *fsnotify.Watcher
+	watched      map[string]bool
+	rename       *rename
+	queue        *Queue
+	log          zerolog.Logger
+	polled       map[string]poll.Snapshot
+	pollLock     *sync.Mutex
+	pollInterval time.Duration
+	roots        map[string]os.FileInfo
+	supervise    time.Duration
+	catchUp      bool
+	limit        int
+
+	// directory counts, read by the metrics
+	watchedCount atomic.Int64
+	polledCount  atomic.Int64
 }
 
-type path struct {
-	Path     string
-	Rewriter autoscan.Rewriter
-	Allowed  autoscan.Filterer
+type rename struct {
+	Path string
+	Time time.Time
+}
+
+// A Path is a monitored path along with its rewrite, filter and queue configuration.
+type Path struct {
+	Path      string
+	Delay     time.Duration
+	Stability time.Duration
+	Rewriter  autoscan.Rewriter
+	Allowed   autoscan.Filterer
 }
 
 func New(c Config) (autoscan.Trigger, error) {
@@ -47,7 +87,52 @@ func New(c Config) (autoscan.Trigger, error) {
 		Str("trigger", "inotify").
 		Logger()
 
-	var paths []path
+	paths, err := NewPaths(c)
+	if err != nil {
+		return nil, err
+	}
+
+	pollInterval := c.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = defaultPollInterval
+	}
+
+	supervise := c.Supervise
+	if supervise <= 0 {
+		supervise = defaultSupervise
+	}
+
+	trigger := func(callback autoscan.ProcessorFunc) {
+		d := &daemon{
+			log:          l,
+			callback:     callback,
+			paths:        paths,
+			watched:      make(map[string]bool),
+			queue:        NewQueue(callback, l, c.Priority),
+			polled:       make(map[string]poll.Snapshot),
+			pollLock:     &sync.Mutex{},
+			pollInterval: pollInterval,
+			roots:        make(map[string]os.FileInfo),
+			supervise:    supervise,
+			catchUp:      c.CatchUp,
+			limit:        maxUserWatches(),
+		}
+
+		// start job(s)
+		if err := d.startMonitoring(); err != nil {
+			l.Error().
+				Err(err).
+				Msg("Failed initialising jobs")
+			return
+		}
+	}
+
+	return trigger, nil
+}
+
+// NewPaths creates the path objects of an inotify configuration.
+func NewPaths(c Config) ([]Path, error) {
+	var paths []Path
 	for _, p := range c.Paths {
 		p := p
 
@@ -61,31 +146,29 @@ func New(c Config) (autoscan.Trigger, error) {
 			return nil, err
 		}
 
-		paths = append(paths, path{
-			Path:     p.Path,
-			Rewriter: rewriter,
-			Allowed:  filterer,
-		})
-	}
-
-	trigger := func(callback autoscan.ProcessorFunc) {
-		d := daemon{
-			log:      l,
-			callback: callback,
-			paths:    paths,
-			queue:    newQueue(callback, l, c.Priority),
+		delay := c.Delay
+		if p.Delay > 0 {
+			delay = p.Delay
+		}
+		if delay <= 0 {
+			delay = defaultDelay
 		}
 
-		// start job(s)
-		if err := d.startMonitoring(); err != nil {
-			l.Error().
-				Err(err).
-				Msg("Failed initialising jobs")
-			return
+		stability := c.Stability
+		if p.Stability > 0 {
+			stability = p.Stability
 		}
+
+		paths = append(paths, Path{
+			Path:      p.Path,
+			Delay:     delay,
+			Stability: stability,
+			Rewriter:  rewriter,
+			Allowed:   filterer,
+		})
 	}
 
-	return trigger, nil
+	return paths, nil
 }
 
 func (d *daemon) startMonitoring() error {
@@ -96,16 +179,59 @@ func (d *daemon) startMonitoring() error {
 	}
 	d.watcher = watcher
 
-	// setup watcher
+	// determine available paths
+	for _, p := range d.paths {
+		fi, err := os.Stat(p.Path)
+		if err != nil {
+			d.log.Warn().
+				Err(err).
+				Str("path", p.Path).
+				Msg("Path is unavailable, it will be watched once available")
+			d.roots[p.Path] = nil
+			continue
+		}
+
+		d.roots[p.Path] = fi
+	}
+
+	// setup watcher, directories which cannot be watched are polled instead
 	for _, p := range d.paths {
+		if d.roots[p.Path] == nil {
+			continue
+		}
+
 		if err := filepath.Walk(p.Path, d.walkFunc); err != nil {
-			_ = d.watcher.Close()
-			return err
+			// the supervisor retries watching the path
+			d.log.Error().
+				Err(err).
+				Str("path", p.Path).
+				Msg("Failed watching path, retrying later")
+
+			d.unwatch(p.Path)
+			d.roots[p.Path] = nil
 		}
 	}
 
+	register(d)
+
+	stats := d.stats()
+	if stats.Polled > 0 {
+		d.log.Warn().
+			Int("directories", stats.Watched+stats.Polled).
+			Int("limit", stats.Limit).
+			Msg("Not all directories can be watched, increase fs.inotify.max_user_watches to watch all directories")
+	}
+
+	d.log.Info().
+		Int("directories", stats.Watched+stats.Polled).
+		Int("watched", stats.Watched).
+		Int("polled", stats.Polled).
+		Int("limit", stats.Limit).
+		Msg("Watching directories")
+
 	// start worker
 	go d.worker()
+	go d.pollWorker()
 
 	return nil
 }
@@ -122,9 +248,18 @@ func (d *daemon) walkFunc(path string, fi os.FileInfo, err error) error {
 	}
 
 	if err := d.watcher.Add(path); err != nil {
+		if errors.Is(err, syscall.ENOSPC) {
+			// watch limit reached, poll the directory tree instead
+			d.fallback(path)
+			return filepath.SkipDir
+		}
+
 		return fmt.Errorf("watch directory: %v: %w", path, err)
 	}
 
+	d.watched[path] = true
+	d.watchedCount.Store(int64(len(d.watched)))
+
 	d.log.Trace().
 		Str("path", path).
 		Msg("Watching directory")
@@ -132,87 +267,57 @@ func (d *daemon) walkFunc(path string, fi os.FileInfo, err error) error {
 	return nil
 }
 
-func (d *daemon) getPathObject(path string) (*path, error) {
-	for _, p := range d.paths {
-		if strings.HasPrefix(path, p.Path) {
+// MatchPath returns the path object which contains the given path.
+func MatchPath(paths []Path, name string) (*Path, error) {
+	for _, p := range paths {
+		root := strings.TrimSuffix(p.Path, string(filepath.Separator))
+		if name == root || strings.HasPrefix(name, root+string(filepath.Separator)) {
 			return &p, nil
 		}
 	}
 
-	return nil, fmt.Errorf("path object not found: %v", path)
+	return nil, fmt.Errorf("path object not found: %v", name)
 }
 
 func (d *daemon) worker() {
 	// close watcher
 	defer d.watcher.Close()
 
+	t := time.NewTicker(d.supervise)
+	defer t.Stop()
+
 	// process events
 	for {
 		select {
+		case <-t.C:
+			d.superviseRoots()
+
 		case event := <-d.watcher.Events:
 			// new filesystem event
 			d.log.Trace().
 				Interface("event", event).
 				Msg("Filesystem event")
 
-			switch {
-			case event.Op&fsnotify.Create == fsnotify.Create:
-				// create
-				fi, err := os.Stat(event.Name)
-				if err != nil {
-					d.log.Error().
-						Err(err).
-						Str("path", event.Name).
-						Msg("Failed retrieving filesystem info")
-					continue
-				}
-
-				// watch new directories
-				if fi.IsDir() {
-					if err := filepath.Walk(event.Name, d.walkFunc); err != nil {
-						d.log.Error().
-							Err(err).
-							Str("path", event.Name).
-							Msg("Failed watching new directory")
-					}
-
-					continue
-				}
-
-			case event.Op&fsnotify.Rename == fsnotify.Rename, event.Op&fsnotify.Remove == fsnotify.Remove:
-				// renamed / removed
-			default:
-				// ignore this event
-				continue
-			}
-
-			// get path object
-			p, err := d.getPathObject(event.Name)
-			if err != nil {
-				d.log.Error().
-					Err(err).
-					Str("path", event.Name).
-					Msg("Failed determining path object")
+			// events of watches which were removed have no name
+			if event.Name == "" {
 				continue
 			}
 
-			// rewrite
-			rewritten := p.Rewriter(event.Name)
-
-			// filter
-			if !p.Allowed(rewritten) {
+			// events without an operation are emitted when the file system got unmounted
+			if event.Op == 0 {
+				d.superviseRoots()
 				continue
 			}
 
-			// get directory where path has an extension
-			if filepath.Ext(rewritten) != "" {
-				// there was most likely a file extension, use the directory
-				rewritten = filepath.Dir(rewritten)
+			switch {
+			case event.Op&fsnotify.Create == fsnotify.Create:
+				d.handleCreate(event.Name)
+			case event.Op&fsnotify.Rename == fsnotify.Rename:
+				d.handleRename(event.Name)
+			case event.Op&fsnotify.Remove == fsnotify.Remove:
+				d.handleRemove(event.Name)
 			}
 
-			// move to queue
-			d.queue.inputs <- rewritten
-
 		case err := <-d.watcher.Errors:
 			d.log.Error().
 				Err(err).
@@ -221,95 +326,130 @@ func (d *daemon) worker() {
 	}
 }
 
-type queue struct {
-	callback autoscan.ProcessorFunc
-	log      zerolog.Logger
-	priority int
-	inputs   chan string
-	scans    map[string]time.Time
-	lock     *sync.Mutex
-}
+func (d *daemon) handleCreate(name string) {
+	fi, err := os.Stat(name)
+	if err != nil {
+		d.log.Error().
+			Err(err).
+			Str("path", name).
+			Msg("Failed retrieving filesystem info")
+		return
+	}
 
-func newQueue(cb autoscan.ProcessorFunc, log zerolog.Logger, priority int) *queue {
-	q := &queue{
-		callback: cb,
-		log:      log,
-		priority: priority,
-		inputs:   make(chan string),
-		scans:    make(map[string]time.Time),
-		lock:     &sync.Mutex{},
+	// a create directly following a rename is the destination of a move within the watched paths
+	source := d.pairRename(name)
+	if source != "" {
+		d.log.Debug().
+			Str("from", source).
+			Str("to", name).
+			Msg("Path moved")
 	}
 
-	go q.worker()
+	if !fi.IsDir() {
+		d.queueFile(name)
+		return
+	}
 
-	return q
-}
+	// watch new directories, including directories moved into the watched paths
+	if err := filepath.Walk(name, d.walkFunc); err != nil {
+		d.log.Error().
+			Err(err).
+			Str("path", name).
+			Msg("Failed watching new directory")
+	}
 
-func (q *queue) add(path string) {
-	// acquire lock
-	q.lock.Lock()
-	defer q.lock.Unlock()
+	if source != "" {
+		// moved directory, scan the new parent folder
+		d.queueFolder(name, filepath.Dir(name))
+		return
+	}
 
-	// queue scan task
-	q.scans[path] = time.Now().Add(10 * time.Second)
+	// new directory, may already contain files when moved from outside the watched paths
+	d.queueFolder(name, name)
 }
 
-func (q *queue) worker() {
-	for {
-		select {
-		case path, ok := <-q.inputs:
-			if !ok {
-				// channel closed
-				return
-			}
+func (d *daemon) handleRename(name string) {
+	// stop watching the old location of a moved directory
+	d.unwatch(name)
 
-			// add path to queue
-			q.add(path)
+	// remember the rename for the create event of the destination
+	d.rename = &rename{
+		Path: name,
+		Time: now(),
+	}
 
-		default:
-			// process queue
-			q.process()
-		}
+	// scan the old parent folder
+	d.queueFolder(name, filepath.Dir(name))
+}
+
+func (d *daemon) handleRemove(name string) {
+	// removed paths cannot be inspected, only watched paths are known to be directories
+	if d.watched[name] {
+		d.unwatch(name)
+		d.queueFolder(name, name)
+		return
 	}
+
+	d.queueFolder(name, filepath.Dir(name))
 }
 
-func (q *queue) process() {
-	// acquire lock
-	q.lock.Lock()
-	defer q.lock.Unlock()
+// pairRename returns the source path of the previous rename event
+// when it occurred within the rename window.
+func (d *daemon) pairRename(name string) string {
+	r := d.rename
+	d.rename = nil
+
+	if r == nil || r.Path == name || now().Sub(r.Time) > renameWindow {
+		return ""
+	}
 
-	// sleep if no scans queued
-	if len(q.scans) == 0 {
-		time.Sleep(100 * time.Millisecond)
+	return r.Path
+}
+
+// unwatch removes the watches of a directory and all its sub-directories.
+func (d *daemon) unwatch(name string) {
+	if !d.watched[name] {
 		return
 	}
 
-	// move scans to processor
-	for p, t := range q.scans {
-		// time has not elapsed
-		if time.Now().Before(t) {
+	for p := range d.watched {
+		if p != name && !strings.HasPrefix(p, name+string(filepath.Separator)) {
 			continue
 		}
 
-		// move to processor
-		err := q.callback(autoscan.Scan{
-			Folder:   filepath.Clean(p),
-			Priority: q.priority,
-			Time:     time.Now(),
-		})
+		// the watch might have already been removed by the kernel
+		_ = d.watcher.Remove(p)
+		delete(d.watched, p)
+		d.watchedCount.Store(int64(len(d.watched)))
 
-		if err != nil {
-			q.log.Error().
-				Err(err).
-				Str("path", p).
-				Msg("Failed moving scan to processor")
-		} else {
-			q.log.Info().
-				Str("path", p).
-				Msg("Scan moved to processor")
-		}
+		d.log.Trace().
+			Str("path", p).
+			Msg("Stopped watching directory")
+	}
+}
+
+// queueFolder moves the folder of a filesystem event to the queue.
+func (d *daemon) queueFolder(name string, folder string) {
+	d.queuePath(name, folder, false)
+}
 
-		// remove queued scan
-		delete(q.scans, p)
+// queueFile moves the parent folder of a new file to the queue.
+func (d *daemon) queueFile(name string) {
+	d.queuePath(name, filepath.Dir(name), true)
+}
+
+func (d *daemon) queuePath(name string, folder string, file bool) {
+	// get path object
+	p, err := MatchPath(d.paths, name)
+	if err != nil {
+		d.log.Error().
+			Err(err).
+			Str("path", name).
+			Msg("Failed determining path object")
+		return
 	}
+
+	d.queue.Add(p, name, folder, file)
 }
+
+var now = time.Now
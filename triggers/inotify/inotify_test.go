@@ -0,0 +1,459 @@
+package inotify
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/rs/zerolog"
+
+	"github.com/cloudbox/autoscan"
+	"github.com/cloudbox/autoscan/triggers/poll"
+)
+
+// newTestDaemon creates a daemon watching root, the queued items are buffered instead of processed.
+func newTestDaemon(t *testing.T, root string) *daemon {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = watcher.Close()
+	})
+
+	rewriter, err := autoscan.NewRewriter(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filterer, err := autoscan.NewFilterer(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &daemon{
+		log: zerolog.Nop(),
+		paths: []Path{{
+			Path:     root,
+			Delay:    time.Second,
+			Rewriter: rewriter,
+			Allowed:  filterer,
+		}},
+		watcher: watcher,
+		watched: make(map[string]bool),
+		queue: &Queue{
+			inputs: make(chan queueItem, 100),
+			scans:  make(map[string]*queuedScan),
+			lock:   &sync.Mutex{},
+		},
+		polled:   make(map[string]poll.Snapshot),
+		pollLock: &sync.Mutex{},
+		roots:    make(map[string]os.FileInfo),
+	}
+}
+
+// queued returns the folders and files of the items which were queued.
+func (d *daemon) queued() []queueItem {
+	items := make([]queueItem, 0)
+	for {
+		select {
+		case item := <-d.queue.inputs:
+			items = append(items, queueItem{Folder: item.Folder, File: item.File})
+		default:
+			return items
+		}
+	}
+}
+
+func (d *daemon) watchedPaths() []string {
+	paths := make([]string, 0, len(d.watched))
+	for p := range d.watched {
+		paths = append(paths, p)
+	}
+
+	sort.Strings(paths)
+	return paths
+}
+
+func TestHandleEvents(t *testing.T) {
+	type Test struct {
+		Name            string
+		Setup           func(root string)
+		Events          func(d *daemon, root string)
+		ExpectedItems   func(root string) []queueItem
+		ExpectedWatched func(root string) []string
+	}
+
+	mkdir := func(p string) {
+		if err := os.MkdirAll(p, os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var testCases = []Test{
+		{
+			Name: "Create file",
+			Setup: func(root string) {
+				mkdir(filepath.Join(root, "TV/Show"))
+				if err := os.WriteFile(filepath.Join(root, "TV/Show/S01E01.mkv"), []byte("x"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			},
+			Events: func(d *daemon, root string) {
+				d.handleCreate(filepath.Join(root, "TV/Show/S01E01.mkv"))
+			},
+			ExpectedItems: func(root string) []queueItem {
+				return []queueItem{
+					{Folder: filepath.Join(root, "TV/Show"), File: filepath.Join(root, "TV/Show/S01E01.mkv")},
+				}
+			},
+			ExpectedWatched: func(root string) []string {
+				return []string{root, filepath.Join(root, "TV"), filepath.Join(root, "TV/Show")}
+			},
+		},
+		{
+			Name: "Directory moved in from outside",
+			Setup: func(root string) {
+				mkdir(filepath.Join(root, "TV"))
+			},
+			Events: func(d *daemon, root string) {
+				mkdir(filepath.Join(root, "TV/Show/Season 1"))
+				d.handleCreate(filepath.Join(root, "TV/Show"))
+			},
+			ExpectedItems: func(root string) []queueItem {
+				return []queueItem{
+					{Folder: filepath.Join(root, "TV/Show")},
+				}
+			},
+			ExpectedWatched: func(root string) []string {
+				return []string{root, filepath.Join(root, "TV"), filepath.Join(root, "TV/Show"), filepath.Join(root, "TV/Show/Season 1")}
+			},
+		},
+		{
+			Name: "Directory moved within the watched paths",
+			Setup: func(root string) {
+				mkdir(filepath.Join(root, "TV/Show"))
+				mkdir(filepath.Join(root, "Movies"))
+			},
+			Events: func(d *daemon, root string) {
+				if err := os.Rename(filepath.Join(root, "TV/Show"), filepath.Join(root, "Movies/Show")); err != nil {
+					t.Fatal(err)
+				}
+
+				d.handleRename(filepath.Join(root, "TV/Show"))
+				d.handleCreate(filepath.Join(root, "Movies/Show"))
+			},
+			ExpectedItems: func(root string) []queueItem {
+				return []queueItem{
+					{Folder: filepath.Join(root, "TV")},
+					{Folder: filepath.Join(root, "Movies")},
+				}
+			},
+			ExpectedWatched: func(root string) []string {
+				return []string{root, filepath.Join(root, "Movies"), filepath.Join(root, "Movies/Show"), filepath.Join(root, "TV")}
+			},
+		},
+		{
+			Name: "Directory moved out of the watched paths",
+			Setup: func(root string) {
+				mkdir(filepath.Join(root, "TV/Show/Season 1"))
+			},
+			Events: func(d *daemon, root string) {
+				if err := os.RemoveAll(filepath.Join(root, "TV/Show")); err != nil {
+					t.Fatal(err)
+				}
+
+				d.handleRename(filepath.Join(root, "TV/Show"))
+			},
+			ExpectedItems: func(root string) []queueItem {
+				return []queueItem{
+					{Folder: filepath.Join(root, "TV")},
+				}
+			},
+			ExpectedWatched: func(root string) []string {
+				return []string{root, filepath.Join(root, "TV")}
+			},
+		},
+		{
+			Name: "Remove watched directory",
+			Setup: func(root string) {
+				mkdir(filepath.Join(root, "TV/Show/Season 1"))
+			},
+			Events: func(d *daemon, root string) {
+				if err := os.RemoveAll(filepath.Join(root, "TV/Show")); err != nil {
+					t.Fatal(err)
+				}
+
+				d.handleRemove(filepath.Join(root, "TV/Show"))
+			},
+			ExpectedItems: func(root string) []queueItem {
+				return []queueItem{
+					{Folder: filepath.Join(root, "TV/Show")},
+				}
+			},
+			ExpectedWatched: func(root string) []string {
+				return []string{root, filepath.Join(root, "TV")}
+			},
+		},
+		{
+			Name: "Remove file",
+			Setup: func(root string) {
+				mkdir(filepath.Join(root, "TV/Show"))
+			},
+			Events: func(d *daemon, root string) {
+				d.handleRemove(filepath.Join(root, "TV/Show/S01E01.mkv"))
+			},
+			ExpectedItems: func(root string) []queueItem {
+				return []queueItem{
+					{Folder: filepath.Join(root, "TV/Show")},
+				}
+			},
+			ExpectedWatched: func(root string) []string {
+				return []string{root, filepath.Join(root, "TV"), filepath.Join(root, "TV/Show")}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			root := t.TempDir()
+			tc.Setup(root)
+
+			d := newTestDaemon(t, root)
+			if err := filepath.Walk(root, d.walkFunc); err != nil {
+				t.Fatal(err)
+			}
+
+			tc.Events(d, root)
+
+			items := d.queued()
+			if expected := tc.ExpectedItems(root); !reflect.DeepEqual(items, expected) {
+				t.Logf("want: %v", expected)
+				t.Logf("got:  %v", items)
+				t.Errorf("Queued items do not equal")
+			}
+
+			watched := d.watchedPaths()
+			if expected := tc.ExpectedWatched(root); !reflect.DeepEqual(watched, expected) {
+				t.Logf("want: %v", expected)
+				t.Logf("got:  %v", watched)
+				t.Errorf("Watched directories do not equal")
+			}
+		})
+	}
+}
+
+func TestPairRename(t *testing.T) {
+	type Test struct {
+		Name     string
+		Rename   *rename
+		Path     string
+		Expected string
+	}
+
+	current := time.Unix(1600000000, 0)
+	now = func() time.Time {
+		return current
+	}
+	defer func() {
+		now = time.Now
+	}()
+
+	var testCases = []Test{
+		{
+			Name:     "No rename",
+			Path:     "/TV/New",
+			Expected: "",
+		},
+		{
+			Name:     "Rename within the window",
+			Rename:   &rename{Path: "/TV/Old", Time: current.Add(-500 * time.Millisecond)},
+			Path:     "/TV/New",
+			Expected: "/TV/Old",
+		},
+		{
+			Name:     "Rename outside the window",
+			Rename:   &rename{Path: "/TV/Old", Time: current.Add(-2 * time.Second)},
+			Path:     "/TV/New",
+			Expected: "",
+		},
+		{
+			Name:     "Rename of the same path",
+			Rename:   &rename{Path: "/TV/New", Time: current},
+			Path:     "/TV/New",
+			Expected: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			d := &daemon{rename: tc.Rename}
+
+			source := d.pairRename(tc.Path)
+			if source != tc.Expected {
+				t.Errorf("Source does not equal: %q, expected %q", source, tc.Expected)
+			}
+
+			if d.rename != nil {
+				t.Errorf("Expected the rename to be consumed")
+			}
+		})
+	}
+}
+
+func TestMatchPath(t *testing.T) {
+	type Test struct {
+		Name     string
+		Path     string
+		Expected string
+		Err      bool
+	}
+
+	paths := []Path{
+		{Path: "/mnt/unionfs/TV"},
+		{Path: "/mnt/unionfs/Movies/"},
+	}
+
+	var testCases = []Test{
+		{
+			Name:     "Root",
+			Path:     "/mnt/unionfs/TV",
+			Expected: "/mnt/unionfs/TV",
+		},
+		{
+			Name:     "Nested",
+			Path:     "/mnt/unionfs/TV/Westworld/Season 1",
+			Expected: "/mnt/unionfs/TV",
+		},
+		{
+			Name:     "Root with a trailing slash",
+			Path:     "/mnt/unionfs/Movies/Interstellar (2014)",
+			Expected: "/mnt/unionfs/Movies/",
+		},
+		{
+			Name: "Sibling with a common prefix",
+			Path: "/mnt/unionfs/TV2/Westworld",
+			Err:  true,
+		},
+		{
+			Name: "Outside of the paths",
+			Path: "/mnt/local/TV",
+			Err:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			p, err := MatchPath(paths, tc.Path)
+			switch {
+			case tc.Err && err == nil:
+				t.Fatalf("Expected an error, got: %v", p.Path)
+			case tc.Err:
+				return
+			case err != nil:
+				t.Fatal(err)
+			}
+
+			if p.Path != tc.Expected {
+				t.Errorf("Path does not equal: %q, expected %q", p.Path, tc.Expected)
+			}
+		})
+	}
+}
+
+func TestSuperviseRoots(t *testing.T) {
+	type Step struct {
+		Name            string
+		Change          func(root string)
+		ExpectedItems   func(root string) []queueItem
+		ExpectedWatched func(root string) []string
+	}
+
+	none := func(root string) []queueItem {
+		return []queueItem{}
+	}
+
+	var steps = []Step{
+		{
+			Name:            "Unavailable",
+			Change:          func(root string) {},
+			ExpectedItems:   none,
+			ExpectedWatched: func(root string) []string { return []string{} },
+		},
+		{
+			Name: "Becomes available",
+			Change: func(root string) {
+				if err := os.MkdirAll(filepath.Join(root, "Show"), os.ModePerm); err != nil {
+					t.Fatal(err)
+				}
+			},
+			ExpectedItems: func(root string) []queueItem {
+				return []queueItem{{Folder: root}}
+			},
+			ExpectedWatched: func(root string) []string {
+				return []string{root, filepath.Join(root, "Show")}
+			},
+		},
+		{
+			Name:          "Intact",
+			Change:        func(root string) {},
+			ExpectedItems: none,
+			ExpectedWatched: func(root string) []string {
+				return []string{root, filepath.Join(root, "Show")}
+			},
+		},
+		{
+			Name: "Removed",
+			Change: func(root string) {
+				if err := os.RemoveAll(root); err != nil {
+					t.Fatal(err)
+				}
+			},
+			ExpectedItems:   none,
+			ExpectedWatched: func(root string) []string { return []string{} },
+		},
+		{
+			Name: "Replaced",
+			Change: func(root string) {
+				if err := os.MkdirAll(root, os.ModePerm); err != nil {
+					t.Fatal(err)
+				}
+			},
+			ExpectedItems: func(root string) []queueItem {
+				return []queueItem{{Folder: root}}
+			},
+			ExpectedWatched: func(root string) []string {
+				return []string{root}
+			},
+		},
+	}
+
+	root := filepath.Join(t.TempDir(), "mount")
+	d := newTestDaemon(t, root)
+	d.catchUp = true
+	d.roots[root] = nil
+
+	for _, step := range steps {
+		step.Change(root)
+		d.superviseRoots()
+
+		items := d.queued()
+		if expected := step.ExpectedItems(root); !reflect.DeepEqual(items, expected) {
+			t.Logf("want: %v", expected)
+			t.Logf("got:  %v", items)
+			t.Errorf("%s: queued items do not equal", step.Name)
+		}
+
+		watched := d.watchedPaths()
+		if expected := step.ExpectedWatched(root); !reflect.DeepEqual(watched, expected) {
+			t.Logf("want: %v", expected)
+			t.Logf("got:  %v", watched)
+			t.Errorf("%s: watched directories do not equal", step.Name)
+		}
+	}
+}
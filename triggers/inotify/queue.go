@@ -0,0 +1,199 @@
+package inotify
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/cloudbox/autoscan"
+)
+
+const (
+	// time between size checks of files which are not yet stable
+	stabilityInterval = time.Second
+)
+
+// A queueItem is a folder which should be scanned after the delay has passed.
+// When File and Stability are set, the scan is held back until the size of the file
+// has not changed for the duration of Stability.
+type queueItem struct {
+	Folder    string
+	File      string
+	Delay     time.Duration
+	Stability time.Duration
+}
+
+type queuedScan struct {
+	time      time.Time
+	stability time.Duration
+	files     map[string]*fileState
+}
+
+type fileState struct {
+	size  int64
+	since time.Time
+}
+
+// A Queue delays the scans of filesystem events until no further events occurred within the delay.
+type Queue struct {
+	callback autoscan.ProcessorFunc
+	log      zerolog.Logger
+	priority int
+	inputs   chan queueItem
+	scans    map[string]*queuedScan
+	lock     *sync.Mutex
+}
+
+// NewQueue creates a Queue which moves the scans to the processor once they are due.
+func NewQueue(cb autoscan.ProcessorFunc, log zerolog.Logger, priority int) *Queue {
+	q := &Queue{
+		callback: cb,
+		log:      log,
+		priority: priority,
+		inputs:   make(chan queueItem),
+		scans:    make(map[string]*queuedScan),
+		lock:     &sync.Mutex{},
+	}
+
+	go q.worker()
+
+	return q
+}
+
+// Add queues the scan of a folder when the path of the event is allowed.
+// When file is set, the name of the event is checked for stability before the folder is scanned.
+func (q *Queue) Add(p *Path, name string, folder string, file bool) {
+	// filter
+	if !p.Allowed(p.Rewriter(name)) {
+		return
+	}
+
+	item := queueItem{
+		Folder:    p.Rewriter(folder),
+		Delay:     p.Delay,
+		Stability: p.Stability,
+	}
+
+	if file {
+		item.File = name
+	}
+
+	q.inputs <- item
+}
+
+func (q *Queue) add(item queueItem) {
+	// acquire lock
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	// queue scan task
+	scan, ok := q.scans[item.Folder]
+	if !ok {
+		scan = &queuedScan{
+			files: make(map[string]*fileState),
+		}
+		q.scans[item.Folder] = scan
+	}
+
+	scan.time = now().Add(item.Delay)
+	if item.Stability > scan.stability {
+		scan.stability = item.Stability
+	}
+
+	if item.File != "" && item.Stability > 0 {
+		scan.files[item.File] = &fileState{size: -1}
+	}
+}
+
+func (q *Queue) worker() {
+	t := time.NewTicker(100 * time.Millisecond)
+	defer t.Stop()
+
+	for {
+		select {
+		case item, ok := <-q.inputs:
+			if !ok {
+				// channel closed
+				return
+			}
+
+			// add item to queue
+			q.add(item)
+
+		case <-t.C:
+			// process queue
+			q.process()
+		}
+	}
+}
+
+func (q *Queue) process() {
+	// acquire lock
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	// move scans to processor
+	for p, scan := range q.scans {
+		// time has not elapsed
+		if now().Before(scan.time) {
+			continue
+		}
+
+		// files are still changing
+		if !scan.stable() {
+			scan.time = now().Add(stabilityInterval)
+			continue
+		}
+
+		// move to processor
+		err := q.callback(autoscan.Scan{
+			Folder:   filepath.Clean(p),
+			Priority: q.priority,
+			Time:     now(),
+		})
+
+		if err != nil {
+			q.log.Error().
+				Err(err).
+				Str("path", p).
+				Msg("Failed moving scan to processor")
+		} else {
+			q.log.Info().
+				Str("path", p).
+				Msg("Scan moved to processor")
+		}
+
+		// remove queued scan
+		delete(q.scans, p)
+	}
+}
+
+// stable checks whether the sizes of all files have stopped changing.
+func (s *queuedScan) stable() bool {
+	stable := true
+
+	for name, state := range s.files {
+		fi, err := os.Stat(name)
+		if err != nil {
+			// file no longer exists
+			delete(s.files, name)
+			continue
+		}
+
+		if fi.Size() != state.size {
+			state.size = fi.Size()
+			state.since = now()
+			stable = false
+			continue
+		}
+
+		if now().Sub(state.since) < s.stability {
+			stable = false
+		}
+	}
+
+	return stable
+}
@@ -0,0 +1,131 @@
+package inotify
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/cloudbox/autoscan"
+)
+
+func TestQueue(t *testing.T) {
+	// the file of the case is used for items with a File
+	const file = "file"
+
+	type Step struct {
+		At       time.Duration
+		Add      []queueItem
+		Size     int
+		Expected []string
+	}
+
+	type Test struct {
+		Name  string
+		Steps []Step
+	}
+
+	var testCases = []Test{
+		{
+			Name: "Debounces events of a folder",
+			Steps: []Step{
+				{At: 0, Add: []queueItem{{Folder: "/TV/Show", Delay: 10 * time.Second}}},
+				{At: 5 * time.Second, Add: []queueItem{{Folder: "/TV/Show", Delay: 10 * time.Second}}},
+				{At: 12 * time.Second},
+				{At: 15 * time.Second, Expected: []string{"/TV/Show"}},
+				{At: 30 * time.Second},
+			},
+		},
+		{
+			Name: "Delays folders independently",
+			Steps: []Step{
+				{At: 0, Add: []queueItem{
+					{Folder: "/TV/Show", Delay: 10 * time.Second},
+					{Folder: "/Movies/Movie", Delay: 20 * time.Second},
+				}},
+				{At: 10 * time.Second, Expected: []string{"/TV/Show"}},
+				{At: 20 * time.Second, Expected: []string{"/Movies/Movie"}},
+			},
+		},
+		{
+			Name: "Waits until the size of a file is stable",
+			Steps: []Step{
+				{At: 0, Size: 1, Add: []queueItem{{Folder: "/TV/Show", File: file, Delay: 10 * time.Second, Stability: 5 * time.Second}}},
+				{At: 10 * time.Second, Size: 2},
+				{At: 11 * time.Second, Size: 3},
+				{At: 12 * time.Second},
+				{At: 14 * time.Second},
+				{At: 16 * time.Second, Expected: []string{"/TV/Show"}},
+			},
+		},
+		{
+			Name: "Ignores the stability of removed files",
+			Steps: []Step{
+				{At: 0, Size: -1, Add: []queueItem{{Folder: "/TV/Show", File: file, Delay: 10 * time.Second, Stability: 5 * time.Second}}},
+				{At: 10 * time.Second, Expected: []string{"/TV/Show"}},
+			},
+		},
+	}
+
+	start := time.Unix(1600000000, 0)
+	defer func() {
+		now = time.Now
+	}()
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			name := filepath.Join(t.TempDir(), file)
+
+			var got []string
+			q := &Queue{
+				callback: func(scans ...autoscan.Scan) error {
+					for _, s := range scans {
+						got = append(got, s.Folder)
+					}
+					return nil
+				},
+				log:   zerolog.Nop(),
+				scans: make(map[string]*queuedScan),
+				lock:  &sync.Mutex{},
+			}
+
+			for _, step := range tc.Steps {
+				now = func() time.Time {
+					return start.Add(step.At)
+				}
+
+				switch {
+				case step.Size > 0:
+					if err := os.WriteFile(name, make([]byte, step.Size), 0644); err != nil {
+						t.Fatal(err)
+					}
+				case step.Size < 0:
+					_ = os.Remove(name)
+				}
+
+				for _, item := range step.Add {
+					if item.File == file {
+						item.File = name
+					}
+
+					q.add(item)
+				}
+
+				got = nil
+				q.process()
+
+				sort.Strings(got)
+				if !reflect.DeepEqual(got, step.Expected) {
+					t.Logf("want: %v", step.Expected)
+					t.Logf("got:  %v", got)
+					t.Errorf("Scans at %v do not equal", step.At)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package inotify
+
+import (
+	"sync"
+)
+
+// WatchStats are the amount of directories watched, or polled once the watch limit was reached.
+type WatchStats struct {
+	Watched int
+	Polled  int
+	Limit   int
+}
+
+var (
+	daemons   = make(map[*daemon]bool)
+	statsLock sync.Mutex
+)
+
+func register(d *daemon) {
+	statsLock.Lock()
+	defer statsLock.Unlock()
+
+	daemons[d] = true
+}
+
+func (d *daemon) stats() WatchStats {
+	return WatchStats{
+		Watched: int(d.watchedCount.Load()),
+		Polled:  int(d.polledCount.Load()),
+		Limit:   d.limit,
+	}
+}
+
+// Stats returns the directory counts of all inotify triggers combined.
+// False is returned when no inotify trigger is running.
+func Stats() (WatchStats, bool) {
+	statsLock.Lock()
+	defer statsLock.Unlock()
+
+	total := WatchStats{}
+	for d := range daemons {
+		s := d.stats()
+		total.Watched += s.Watched
+		total.Polled += s.Polled
+		total.Limit = s.Limit
+	}
+
+	return total, len(daemons) > 0
+}
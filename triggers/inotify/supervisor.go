@@ -0,0 +1,66 @@
+package inotify
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	defaultSupervise = time.Minute
+)
+
+// superviseRoots re-establishes the watches of paths which were unmounted, removed or replaced.
+// A path which got replaced, e.g. by remounting a FUSE file system, is detected by comparing
+// the file info of the path to the file info at the time the path was watched.
+func (d *daemon) superviseRoots() {
+	for _, p := range d.paths {
+		previous := d.roots[p.Path]
+
+		fi, err := os.Stat(p.Path)
+		switch {
+		case err != nil && previous == nil:
+			// still unavailable
+			continue
+
+		case err != nil:
+			// watches were lost
+			d.log.Warn().
+				Err(err).
+				Str("path", p.Path).
+				Msg("Path is unavailable, it will be watched once available")
+
+			d.unwatch(p.Path)
+			d.roots[p.Path] = nil
+			continue
+
+		case previous != nil && os.SameFile(previous, fi):
+			// watches are intact
+			continue
+		}
+
+		// path became available or was replaced
+		d.unwatch(p.Path)
+		if err := filepath.Walk(p.Path, d.walkFunc); err != nil {
+			d.log.Error().
+				Err(err).
+				Str("path", p.Path).
+				Msg("Failed watching path")
+
+			d.roots[p.Path] = nil
+			continue
+		}
+
+		d.roots[p.Path] = fi
+
+		d.log.Info().
+			Str("path", p.Path).
+			Int("watched", len(d.watched)).
+			Msg("Path is available, watching directories")
+
+		// catch up on changes which happened while the path was not watched
+		if d.catchUp {
+			d.queueFolder(p.Path, p.Path)
+		}
+	}
+}
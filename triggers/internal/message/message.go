@@ -0,0 +1,94 @@
+// Package message translates the JSON messages of the message broker triggers into scans.
+package message
+
+import (
+	"encoding/json"
+	"errors"
+	"path"
+	"time"
+
+	"github.com/cloudbox/autoscan"
+)
+
+// A Message holds the paths to scan, optionally along with the event kind and a priority
+// which overrides the priority of the trigger.
+type Message struct {
+	Event    string   `json:"event"`
+	Path     string   `json:"path"`
+	Paths    []string `json:"paths"`
+	Priority *int     `json:"priority"`
+}
+
+// A Handler translates messages into scans.
+// When Dirname is set, the parent folders of the paths are scanned.
+type Handler struct {
+	Priority int
+	Dirname  bool
+	Rewrite  autoscan.Rewriter
+	Allowed  autoscan.Filterer
+}
+
+// Scans decodes a message and translates its paths into scans.
+func (h Handler) Scans(data []byte) (*Message, []autoscan.Scan, error) {
+	m := new(Message)
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, nil, err
+	}
+
+	scans, err := h.MessageScans(m)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return m, scans, nil
+}
+
+// AllPaths returns the path along with the paths of a message, leaving out empty paths.
+func (m *Message) AllPaths() []string {
+	paths := make([]string, 0, len(m.Paths)+1)
+	for _, p := range append([]string{m.Path}, m.Paths...) {
+		if p != "" {
+			paths = append(paths, p)
+		}
+	}
+
+	return paths
+}
+
+// MessageScans translates the paths of a decoded message into scans.
+func (h Handler) MessageScans(m *Message) ([]autoscan.Scan, error) {
+	paths := m.AllPaths()
+	if len(paths) == 0 {
+		return nil, errors.New("message contains no paths")
+	}
+
+	priority := h.Priority
+	if m.Priority != nil {
+		priority = *m.Priority
+	}
+
+	unique := make(map[string]bool)
+	scans := make([]autoscan.Scan, 0, len(paths))
+
+	for _, p := range paths {
+		if h.Dirname {
+			p = path.Dir(p)
+		}
+
+		folderPath := h.Rewrite(path.Clean(p))
+		if unique[folderPath] || !h.Allowed(folderPath) {
+			continue
+		}
+
+		unique[folderPath] = true
+		scans = append(scans, autoscan.Scan{
+			Folder:   folderPath,
+			Priority: priority,
+			Time:     now(),
+		})
+	}
+
+	return scans, nil
+}
+
+var now = time.Now
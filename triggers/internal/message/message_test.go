@@ -0,0 +1,102 @@
+package message
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cloudbox/autoscan"
+)
+
+func TestScans(t *testing.T) {
+	type Test struct {
+		Name     string
+		Dirname  bool
+		Message  string
+		Expected []autoscan.Scan
+		Error    bool
+	}
+
+	rewriter, err := autoscan.NewRewriter([]autoscan.Rewrite{{
+		From: "^/downloads/",
+		To:   "/mnt/unionfs/Media/",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filterer, err := autoscan.NewFilterer(nil, []string{`\.nfo$`})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	currentTime := time.Now()
+	now = func() time.Time {
+		return currentTime
+	}
+
+	var testCases = []Test{
+		{
+			Name:    "Single path",
+			Message: `{"event": "created", "path": "/downloads/Movies/Interstellar (2014)"}`,
+			Expected: []autoscan.Scan{
+				{Folder: "/mnt/unionfs/Media/Movies/Interstellar (2014)", Priority: 2, Time: currentTime},
+			},
+		},
+		{
+			Name:    "Multiple paths with priority",
+			Message: `{"paths": ["/downloads/TV/Westworld/Season 1", "/downloads/TV/Westworld/Season 1/", "/downloads/TV/Westworld/Season 2"], "priority": 7}`,
+			Expected: []autoscan.Scan{
+				{Folder: "/mnt/unionfs/Media/TV/Westworld/Season 1", Priority: 7, Time: currentTime},
+				{Folder: "/mnt/unionfs/Media/TV/Westworld/Season 2", Priority: 7, Time: currentTime},
+			},
+		},
+		{
+			Name:    "Parent folders of files",
+			Dirname: true,
+			Message: `{"paths": ["/downloads/Movies/Tenet (2020)/Tenet.mkv", "/downloads/Movies/Tenet (2020)/Tenet.nfo"]}`,
+			Expected: []autoscan.Scan{
+				{Folder: "/mnt/unionfs/Media/Movies/Tenet (2020)", Priority: 2, Time: currentTime},
+			},
+		},
+		{
+			Name:    "Message without paths",
+			Message: `{"event": "created"}`,
+			Error:   true,
+		},
+		{
+			Name:    "Invalid JSON",
+			Message: `This is an invalid JSON message`,
+			Error:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			h := Handler{
+				Priority: 2,
+				Dirname:  tc.Dirname,
+				Rewrite:  rewriter,
+				Allowed:  filterer,
+			}
+
+			_, scans, err := h.Scans([]byte(tc.Message))
+			if tc.Error {
+				if err == nil {
+					t.Errorf("Expected an error, got: %v", scans)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(scans, tc.Expected) {
+				t.Logf("want: %v", tc.Expected)
+				t.Logf("got:  %v", scans)
+				t.Errorf("Scans do not equal")
+			}
+		})
+	}
+}
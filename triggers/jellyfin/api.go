@@ -0,0 +1,87 @@
+package jellyfin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/cloudbox/autoscan"
+)
+
+type apiClient struct {
+	client  *http.Client
+	baseURL string
+	token   string
+}
+
+func newAPIClient(baseURL string, token string) *apiClient {
+	return &apiClient{
+		client:  &http.Client{},
+		baseURL: baseURL,
+		token:   token,
+	}
+}
+
+func (c apiClient) do(req *http.Request) (*http.Response, error) {
+	req.Header.Set("X-Emby-Token", c.token)
+	req.Header.Set("Accept", "application/json") // Force JSON Response.
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return res, nil
+	}
+
+	// statusCode not in the 2xx range, close response
+	res.Body.Close()
+
+	switch res.StatusCode {
+	case 401:
+		return nil, fmt.Errorf("invalid token: %s", res.Status)
+	default:
+		return nil, fmt.Errorf("%s", res.Status)
+	}
+}
+
+// Item returns the item with the given ID, nil is returned when the item does not exist.
+func (c apiClient) Item(id string) (*item, error) {
+	reqURL := autoscan.JoinURL(c.baseURL, "Items")
+	req, err := http.NewRequest("GET", reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed creating item request: %w", err)
+	}
+
+	q := url.Values{}
+	q.Add("Ids", id)
+	q.Add("Fields", "Path")
+	q.Add("Recursive", "true")
+	req.URL.RawQuery = q.Encode()
+
+	res, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("item: %w", err)
+	}
+
+	defer res.Body.Close()
+
+	type Response struct {
+		Items []item `json:"Items"`
+	}
+
+	resp := new(Response)
+	if err := json.NewDecoder(res.Body).Decode(resp); err != nil {
+		return nil, fmt.Errorf("failed decoding item response: %w", err)
+	}
+
+	for _, i := range resp.Items {
+		if i.Path != "" {
+			return &i, nil
+		}
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,216 @@
+package jellyfin
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/hlog"
+
+	"github.com/cloudbox/autoscan"
+)
+
+type Config struct {
+	Name      string             `yaml:"name"`
+	URL       string             `yaml:"url"`
+	Token     string             `yaml:"token"`
+	Priority  int                `yaml:"priority"`
+	Rewrite   []autoscan.Rewrite `yaml:"rewrite"`
+	Verbosity string             `yaml:"verbosity"`
+}
+
+// New creates an autoscan-compatible HTTP Trigger for the webhooks of the Jellyfin Webhook plugin and Emby.
+// When the URL and token are given, the path of items is looked up when it is missing from the webhook.
+func New(c Config) (autoscan.HTTPTrigger, error) {
+	rewriter, err := autoscan.NewRewriter(c.Rewrite)
+	if err != nil {
+		return nil, err
+	}
+
+	var api *apiClient
+	if c.URL != "" && c.Token != "" {
+		api = newAPIClient(c.URL, c.Token)
+	}
+
+	trigger := func(callback autoscan.ProcessorFunc) http.Handler {
+		return handler{
+			callback: callback,
+			priority: c.Priority,
+			rewrite:  rewriter,
+			api:      api,
+		}
+	}
+
+	return trigger, nil
+}
+
+type handler struct {
+	priority int
+	rewrite  autoscan.Rewriter
+	callback autoscan.ProcessorFunc
+	api      *apiClient
+}
+
+type item struct {
+	ID       string `json:"Id"`
+	Name     string `json:"Name"`
+	Type     string `json:"Type"`
+	Path     string `json:"Path"`
+	IsFolder *bool  `json:"IsFolder"`
+}
+
+// event holds the fields of both the Jellyfin Webhook plugin and Emby webhooks.
+type event struct {
+	// Jellyfin Webhook plugin
+	ServerID         string `json:"ServerId"`
+	NotificationType string `json:"NotificationType"`
+	ItemID           string `json:"ItemId"`
+	ItemType         string `json:"ItemType"`
+	ItemPath         string `json:"ItemPath"`
+
+	// Emby
+	Event  string `json:"Event"`
+	Item   item   `json:"Item"`
+	Server struct {
+		ID string `json:"Id"`
+	} `json:"Server"`
+}
+
+// serverID returns the ID of the server which sent the event regardless of the webhook format.
+func (e event) serverID() string {
+	if e.ServerID != "" {
+		return e.ServerID
+	}
+
+	return e.Server.ID
+}
+
+// item returns the item of the event regardless of the webhook format.
+func (e event) item() item {
+	i := e.Item
+	if i.ID == "" {
+		i.ID = e.ItemID
+	}
+	if i.Type == "" {
+		i.Type = e.ItemType
+	}
+	if i.Path == "" {
+		i.Path = e.ItemPath
+	}
+
+	return i
+}
+
+// folderTypes are the item types of which the path is a folder.
+var folderTypes = map[string]bool{
+	"series":           true,
+	"season":           true,
+	"musicalbum":       true,
+	"musicartist":      true,
+	"folder":           true,
+	"boxset":           true,
+	"collectionfolder": true,
+}
+
+// folder returns the folder to scan for an item.
+func (i item) folder() string {
+	isFolder := folderTypes[strings.ToLower(i.Type)]
+	if i.IsFolder != nil {
+		isFolder = *i.IsFolder
+	}
+
+	if isFolder {
+		return i.Path
+	}
+
+	return path.Dir(i.Path)
+}
+
+func (h handler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	var err error
+	rlog := hlog.FromRequest(r)
+
+	e := new(event)
+	err = json.NewDecoder(r.Body).Decode(e)
+	if err != nil {
+		rlog.Error().Err(err).Msg("Failed decoding request")
+		rw.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	rlog.Trace().Interface("event", e).Msg("Received JSON body")
+
+	eventType := e.NotificationType
+	if eventType == "" {
+		eventType = e.Event
+	}
+
+	switch strings.ToLower(eventType) {
+	case "itemadded", "library.new", "itemdeleted", "library.deleted":
+	default:
+		rlog.Debug().Str("event", eventType).Msg("Ignoring event")
+		rw.WriteHeader(http.StatusOK)
+		return
+	}
+
+	i := e.item()
+	if i.Path == "" && i.ID != "" && h.api != nil {
+		// look up the path of the item
+		found, err := h.api.Item(i.ID)
+		if err != nil {
+			rlog.Error().
+				Err(err).
+				Str("id", i.ID).
+				Msg("Failed retrieving item")
+			rw.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if found == nil {
+			// deleted items can no longer be retrieved
+			rlog.Warn().
+				Str("id", i.ID).
+				Str("event", eventType).
+				Msg("Item not found, include the item path in the webhook")
+			rw.WriteHeader(http.StatusOK)
+			return
+		}
+
+		if i.Type == "" {
+			i.Type = found.Type
+		}
+		i.Path = found.Path
+		i.IsFolder = found.IsFolder
+	}
+
+	if i.Path == "" {
+		rlog.Error().Msg("Required fields are missing")
+		rw.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// the server which sent the webhook already knows about the item
+	scan := autoscan.Scan{
+		Folder:   h.rewrite(i.folder()),
+		Priority: h.priority,
+		Time:     now(),
+		Origin:   e.serverID(),
+	}
+
+	err = h.callback(scan)
+	if err != nil {
+		rlog.Error().Err(err).Msg("Processor could not process scans")
+		rw.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	rw.WriteHeader(http.StatusOK)
+	rlog.Info().
+		Str("path", scan.Folder).
+		Str("event", eventType).
+		Msg("Scan moved to processor")
+}
+
+var now = time.Now
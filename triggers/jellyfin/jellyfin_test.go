@@ -0,0 +1,231 @@
+package jellyfin
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cloudbox/autoscan"
+)
+
+func TestHandler(t *testing.T) {
+	type Given struct {
+		Config  Config
+		Fixture string
+	}
+
+	type Expected struct {
+		Scans      []autoscan.Scan
+		StatusCode int
+	}
+
+	type Test struct {
+		Name     string
+		Given    Given
+		Expected Expected
+	}
+
+	// jellyfin api serving the item fixtures
+	api := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Emby-Token") != "token" {
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		rw.Header().Set("Content-Type", "application/json")
+
+		b, err := os.ReadFile("testdata/items_" + r.URL.Query().Get("Ids") + ".json")
+		if err != nil {
+			_, _ = rw.Write([]byte(`{"Items":[],"TotalRecordCount":0}`))
+			return
+		}
+
+		_, _ = rw.Write(b)
+	}))
+	defer api.Close()
+
+	standardConfig := Config{
+		Name:     "jellyfin",
+		URL:      api.URL,
+		Token:    "token",
+		Priority: 5,
+		Rewrite: []autoscan.Rewrite{{
+			From: "/data/*",
+			To:   "/mnt/unionfs/Media/$1",
+		}},
+	}
+
+	currentTime := time.Now()
+	now = func() time.Time {
+		return currentTime
+	}
+
+	var testCases = []Test{
+		{
+			"Jellyfin ItemAdded with item path",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/jellyfin_item_added.json",
+			},
+			Expected{
+				StatusCode: 200,
+				Scans: []autoscan.Scan{
+					{
+						Folder:   "/mnt/unionfs/Media/Movies/Interstellar (2014)",
+						Priority: 5,
+						Time:     currentTime,
+						Origin:   "4a2f0c3e8d1b4f6a9c7e5d3b1a0f2e4c",
+					},
+				},
+			},
+		},
+		{
+			"Jellyfin ItemAdded with item lookup",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/jellyfin_item_added_lookup.json",
+			},
+			Expected{
+				StatusCode: 200,
+				Scans: []autoscan.Scan{
+					{
+						Folder:   "/mnt/unionfs/Media/TV/Westworld",
+						Priority: 5,
+						Time:     currentTime,
+						Origin:   "4a2f0c3e8d1b4f6a9c7e5d3b1a0f2e4c",
+					},
+				},
+			},
+		},
+		{
+			"Returns 200 on deleted items which cannot be retrieved without emitting a scan",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/jellyfin_item_deleted_unknown.json",
+			},
+			Expected{
+				StatusCode: 200,
+			},
+		},
+		{
+			"Returns 200 on other events without emitting a scan",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/jellyfin_playback_start.json",
+			},
+			Expected{
+				StatusCode: 200,
+			},
+		},
+		{
+			"Returns bad request when the path cannot be determined",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/jellyfin_missing_path.json",
+			},
+			Expected{
+				StatusCode: 400,
+			},
+		},
+		{
+			"Returns 500 when the item lookup fails",
+			Given{
+				Config: Config{
+					URL:   api.URL,
+					Token: "invalid",
+				},
+				Fixture: "testdata/jellyfin_item_added_lookup.json",
+			},
+			Expected{
+				StatusCode: 500,
+			},
+		},
+		{
+			"Emby library.new",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/emby_library_new.json",
+			},
+			Expected{
+				StatusCode: 200,
+				Scans: []autoscan.Scan{
+					{
+						Folder:   "/mnt/unionfs/Media/Movies/Parasite (2019)",
+						Priority: 5,
+						Time:     currentTime,
+						Origin:   "9d8c7b6a5f4e3d2c1b0a9f8e7d6c5b4a",
+					},
+				},
+			},
+		},
+		{
+			"Emby library.deleted of a folder",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/emby_library_deleted.json",
+			},
+			Expected{
+				StatusCode: 200,
+				Scans: []autoscan.Scan{
+					{
+						Folder:   "/mnt/unionfs/Media/TV/Westworld/Season 2",
+						Priority: 5,
+						Time:     currentTime,
+					},
+				},
+			},
+		},
+		{
+			"Returns bad request on invalid JSON",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/invalid.json",
+			},
+			Expected{
+				StatusCode: 400,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			callback := func(scans ...autoscan.Scan) error {
+				if !reflect.DeepEqual(tc.Expected.Scans, scans) {
+					t.Log(scans)
+					t.Log(tc.Expected.Scans)
+					t.Errorf("Scans do not equal")
+					return errors.New("Scans do not equal")
+				}
+
+				return nil
+			}
+
+			trigger, err := New(tc.Given.Config)
+			if err != nil {
+				t.Fatalf("Could not create Jellyfin Trigger: %v", err)
+			}
+
+			server := httptest.NewServer(trigger(callback))
+			defer server.Close()
+
+			request, err := os.Open(tc.Given.Fixture)
+			if err != nil {
+				t.Fatalf("Could not open the fixture: %s", tc.Given.Fixture)
+			}
+
+			res, err := http.Post(server.URL, "application/json", request)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+
+			defer res.Body.Close()
+			if res.StatusCode != tc.Expected.StatusCode {
+				t.Errorf("Status codes do not match: %d vs %d", res.StatusCode, tc.Expected.StatusCode)
+			}
+		})
+	}
+}
@@ -43,13 +43,71 @@ type handler struct {
 	callback autoscan.ProcessorFunc
 }
 
+type trackFile struct {
+	Path string `json:"path"`
+
+	// PreviousPath is only included in renamed track files.
+	PreviousPath string `json:"previousPath"`
+}
+
 type lidarrEvent struct {
 	Type    string `json:"eventType"`
 	Upgrade bool   `json:"isUpgrade"`
 
-	Files []struct {
-		Path string
-	} `json:"trackFiles"`
+	Files []trackFile `json:"trackFiles"`
+	File  trackFile   `json:"trackFile"`
+
+	RenamedFiles []trackFile `json:"renamedTrackFiles"`
+
+	Artist struct {
+		Path string `json:"path"`
+	} `json:"artist"`
+}
+
+// paths returns the folders affected by the event.
+// Nil is returned for events which do not affect any folders.
+func (e lidarrEvent) paths() []string {
+	paths := make([]string, 0)
+
+	addFile := func(filePath string) {
+		if filePath != "" {
+			paths = append(paths, path.Dir(filePath))
+		}
+	}
+
+	switch strings.ToLower(e.Type) {
+	case "download":
+		for _, f := range e.Files {
+			addFile(f.Path)
+		}
+
+	// the tags of a track file were rewritten
+	case "retag", "trackretag":
+		addFile(e.File.Path)
+
+	// scan both the previous and the current folder
+	case "rename":
+		for _, f := range e.RenamedFiles {
+			addFile(f.PreviousPath)
+			addFile(f.Path)
+		}
+
+		// older versions do not include the renamed track files, scan the folder of the artist instead
+		if len(e.RenamedFiles) == 0 && e.Artist.Path != "" {
+			paths = append(paths, e.Artist.Path)
+		}
+
+	// an album or artist was deleted, scan the folder of the artist
+	case "albumdelete", "artistdelete":
+		if e.Artist.Path != "" {
+			paths = append(paths, e.Artist.Path)
+		}
+
+	default:
+		return nil
+	}
+
+	return paths
 }
 
 func (h handler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
@@ -72,7 +130,15 @@ func (h handler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !strings.EqualFold(event.Type, "Download") || len(event.Files) == 0 {
+	paths := event.paths()
+	if paths == nil {
+		// Grab, Health and other events without file system changes
+		l.Debug().Str("event", event.Type).Msg("Ignoring event")
+		rw.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if len(paths) == 0 {
 		l.Error().Msg("Required fields are missing")
 		rw.WriteHeader(http.StatusBadRequest)
 		return
@@ -81,8 +147,8 @@ func (h handler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 	unique := make(map[string]bool)
 	scans := make([]autoscan.Scan, 0)
 
-	for _, f := range event.Files {
-		folderPath := path.Dir(h.rewrite(f.Path))
+	for _, p := range paths {
+		folderPath := h.rewrite(p)
 		if _, ok := unique[folderPath]; ok {
 			continue
 		}
@@ -104,10 +170,12 @@ func (h handler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 	}
 
 	rw.WriteHeader(http.StatusOK)
-	l.Info().
-		Str("path", scans[0].Folder).
-		Str("event", event.Type).
-		Msg("Scan moved to processor")
+	for _, scan := range scans {
+		l.Info().
+			Str("path", scan.Folder).
+			Str("event", event.Type).
+			Msg("Scan moved to processor")
+	}
 }
 
 var now = time.Now
@@ -80,6 +80,116 @@ func TestHandler(t *testing.T) {
 					}},
 			},
 		},
+		{
+			"Scans the previous and current folders on Rename",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/rename.json",
+			},
+			Expected{
+				StatusCode: 200,
+				Scans: []autoscan.Scan{
+					{
+						Folder:   "/mnt/unionfs/Media/Music/Marshmello/Joytime III",
+						Priority: 5,
+						Time:     currentTime,
+					},
+					{
+						Folder:   "/mnt/unionfs/Media/Music/Marshmello/Joytime III (2019)",
+						Priority: 5,
+						Time:     currentTime,
+					},
+				},
+			},
+		},
+		{
+			"Scans the artist folder on Rename without renamed files",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/rename_artist.json",
+			},
+			Expected{
+				StatusCode: 200,
+				Scans: []autoscan.Scan{
+					{
+						Folder:   "/mnt/unionfs/Media/Music/Marshmello",
+						Priority: 5,
+						Time:     currentTime,
+					},
+				},
+			},
+		},
+		{
+			"Scans the album folder on Retag",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/retag.json",
+			},
+			Expected{
+				StatusCode: 200,
+				Scans: []autoscan.Scan{
+					{
+						Folder:   "/mnt/unionfs/Media/Music/Marshmello/Joytime III (2019)",
+						Priority: 5,
+						Time:     currentTime,
+					},
+				},
+			},
+		},
+		{
+			"Scans the artist folder on AlbumDelete",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/album_delete.json",
+			},
+			Expected{
+				StatusCode: 200,
+				Scans: []autoscan.Scan{
+					{
+						Folder:   "/mnt/unionfs/Media/Music/Marshmello",
+						Priority: 5,
+						Time:     currentTime,
+					},
+				},
+			},
+		},
+		{
+			"Scans the artist folder on ArtistDelete",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/artist_delete.json",
+			},
+			Expected{
+				StatusCode: 200,
+				Scans: []autoscan.Scan{
+					{
+						Folder:   "/mnt/unionfs/Media/Music/blink-182",
+						Priority: 5,
+						Time:     currentTime,
+					},
+				},
+			},
+		},
+		{
+			"Returns bad request when required fields are missing",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/missing_fields.json",
+			},
+			Expected{
+				StatusCode: 400,
+			},
+		},
+		{
+			"Returns 200 on Grab event without emitting a scan",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/grab.json",
+			},
+			Expected{
+				StatusCode: 200,
+			},
+		},
 		{
 			"Returns bad request on invalid JSON",
 			Given{
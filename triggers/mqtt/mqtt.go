@@ -0,0 +1,190 @@
+package mqtt
+
+import (
+	"fmt"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/rs/zerolog"
+
+	"github.com/cloudbox/autoscan"
+	"github.com/cloudbox/autoscan/triggers/internal/message"
+)
+
+type Config struct {
+	Broker    string             `yaml:"broker"`
+	Topic     string             `yaml:"topic"`
+	ClientID  string             `yaml:"client-id"`
+	Username  string             `yaml:"username"`
+	Password  string             `yaml:"password"`
+	QoS       *byte              `yaml:"qos"`
+	Priority  int                `yaml:"priority"`
+	Dirname   bool               `yaml:"dirname"`
+	Verbosity string             `yaml:"verbosity"`
+	Rewrite   []autoscan.Rewrite `yaml:"rewrite"`
+	Include   []string           `yaml:"include"`
+	Exclude   []string           `yaml:"exclude"`
+}
+
+const (
+	defaultClientID = "autoscan"
+	defaultQoS      = 1
+
+	minBackoff = time.Second
+	maxBackoff = time.Minute
+)
+
+type daemon struct {
+	message.Handler
+	callback autoscan.ProcessorFunc
+	topic    string
+	qos      byte
+	log      zerolog.Logger
+}
+
+// New creates an autoscan-compatible Trigger which subscribes to an MQTT topic.
+// Messages are only acknowledged once the scans are stored.
+// MQTT has no negative acknowledgements, instead storing the scans is retried until it succeeds,
+// unacknowledged messages are redelivered by the broker when the session is resumed.
+func New(c Config) (autoscan.Trigger, error) {
+	l := autoscan.GetLogger(c.Verbosity).With().
+		Str("trigger", "mqtt").
+		Str("topic", c.Topic).
+		Logger()
+
+	if c.Broker == "" || c.Topic == "" {
+		return nil, fmt.Errorf("mqtt broker and topic are required: %w", autoscan.ErrFatal)
+	}
+
+	rewriter, err := autoscan.NewRewriter(c.Rewrite)
+	if err != nil {
+		return nil, err
+	}
+
+	filterer, err := autoscan.NewFilterer(c.Include, c.Exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	clientID := c.ClientID
+	if clientID == "" {
+		clientID = defaultClientID
+	}
+
+	var qos byte = defaultQoS
+	if c.QoS != nil {
+		qos = *c.QoS
+	}
+
+	if qos > 2 {
+		return nil, fmt.Errorf("invalid mqtt qos: %d: %w", qos, autoscan.ErrFatal)
+	}
+
+	trigger := func(callback autoscan.ProcessorFunc) {
+		d := &daemon{
+			Handler: message.Handler{
+				Priority: c.Priority,
+				Dirname:  c.Dirname,
+				Rewrite:  rewriter,
+				Allowed:  filterer,
+			},
+			callback: callback,
+			topic:    c.Topic,
+			qos:      qos,
+			log:      l,
+		}
+
+		opts := mqtt.NewClientOptions().
+			AddBroker(c.Broker).
+			SetClientID(clientID).
+			SetUsername(c.Username).
+			SetPassword(c.Password).
+			// keep the session, and thereby unacknowledged messages, across reconnects
+			SetCleanSession(false).
+			SetAutoAckDisabled(true).
+			SetAutoReconnect(true).
+			SetConnectRetry(true).
+			SetConnectRetryInterval(minBackoff).
+			SetMaxReconnectInterval(maxBackoff).
+			SetOnConnectHandler(d.onConnect).
+			SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+				l.Warn().Err(err).Msg("Connection lost, reconnecting")
+			})
+
+		// the client keeps retrying the initial connection in the background
+		client := mqtt.NewClient(opts)
+		client.Connect()
+	}
+
+	return trigger, nil
+}
+
+// onConnect subscribes to the topic on every (re)connect.
+func (d *daemon) onConnect(client mqtt.Client) {
+	token := client.Subscribe(d.topic, d.qos, d.handleMessage)
+	go func() {
+		token.Wait()
+		if err := token.Error(); err != nil {
+			d.log.Error().
+				Err(err).
+				Msg("Failed subscribing")
+			return
+		}
+
+		d.log.Info().Msg("Subscribed")
+	}()
+}
+
+func (d *daemon) handleMessage(_ mqtt.Client, msg mqtt.Message) {
+	m, scans, err := d.Scans(msg.Payload())
+	if err != nil {
+		// invalid messages will never succeed, acknowledge to drop them
+		d.log.Error().
+			Err(err).
+			Str("data", string(msg.Payload())).
+			Msg("Failed decoding message")
+		msg.Ack()
+		return
+	}
+
+	// the broker only redelivers unacknowledged messages on reconnect, retry until the scans are stored
+	for attempt := 1; len(scans) > 0; attempt++ {
+		err := d.callback(scans...)
+		if err == nil {
+			break
+		}
+
+		delay := backoff(attempt)
+		d.log.Error().
+			Err(err).
+			Dur("retry", delay).
+			Msg("Processor could not process scans")
+
+		sleep(delay)
+	}
+
+	msg.Ack()
+
+	for _, scan := range scans {
+		d.log.Info().
+			Str("path", scan.Folder).
+			Str("event", m.Event).
+			Msg("Scan moved to processor")
+	}
+}
+
+// backoff returns the exponential delay before the given attempt.
+func backoff(attempts int) time.Duration {
+	delay := minBackoff
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+
+	return delay
+}
+
+var sleep = time.Sleep
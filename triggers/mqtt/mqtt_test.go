@@ -0,0 +1,121 @@
+package mqtt
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/cloudbox/autoscan"
+	"github.com/cloudbox/autoscan/triggers/internal/message"
+)
+
+type fakeMessage struct {
+	payload []byte
+	acks    int
+}
+
+func (m *fakeMessage) Duplicate() bool   { return false }
+func (m *fakeMessage) Qos() byte         { return 1 }
+func (m *fakeMessage) Retained() bool    { return false }
+func (m *fakeMessage) Topic() string     { return "downloads/landed" }
+func (m *fakeMessage) MessageID() uint16 { return 1 }
+func (m *fakeMessage) Payload() []byte   { return m.payload }
+func (m *fakeMessage) Ack()              { m.acks++ }
+
+func TestHandleMessage(t *testing.T) {
+	type Test struct {
+		Name     string
+		Payload  string
+		Failures int
+		Expected []autoscan.Scan
+		Delays   []time.Duration
+	}
+
+	var testCases = []Test{
+		{
+			Name:    "Stores the scans",
+			Payload: `{"path": "/downloads/Movies/Interstellar (2014)"}`,
+			Expected: []autoscan.Scan{
+				{Folder: "/mnt/unionfs/Media/Movies/Interstellar (2014)", Priority: 2},
+			},
+		},
+		{
+			Name:     "Retries a failing processor before acknowledging",
+			Payload:  `{"path": "/downloads/Movies/Interstellar (2014)"}`,
+			Failures: 2,
+			Expected: []autoscan.Scan{
+				{Folder: "/mnt/unionfs/Media/Movies/Interstellar (2014)", Priority: 2},
+			},
+			Delays: []time.Duration{time.Second, 2 * time.Second},
+		},
+		{
+			Name:    "Drops invalid messages",
+			Payload: `{"path": `,
+		},
+	}
+
+	rewriter, _ := autoscan.NewRewriter([]autoscan.Rewrite{{
+		From: "^/downloads/",
+		To:   "/mnt/unionfs/Media/",
+	}})
+
+	filterer, _ := autoscan.NewFilterer(nil, nil)
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var delays []time.Duration
+			sleep = func(d time.Duration) {
+				delays = append(delays, d)
+			}
+			defer func() {
+				sleep = time.Sleep
+			}()
+
+			var scans []autoscan.Scan
+			failures := tc.Failures
+
+			d := &daemon{
+				Handler: message.Handler{
+					Priority: 2,
+					Rewrite:  rewriter,
+					Allowed:  filterer,
+				},
+				callback: func(s ...autoscan.Scan) error {
+					if failures > 0 {
+						failures--
+						return errors.New("database is locked")
+					}
+
+					for _, scan := range s {
+						scan.Time = time.Time{}
+						scans = append(scans, scan)
+					}
+					return nil
+				},
+				log: zerolog.Nop(),
+			}
+
+			msg := &fakeMessage{payload: []byte(tc.Payload)}
+			d.handleMessage(nil, msg)
+
+			if msg.acks != 1 {
+				t.Errorf("Expected the message to be acknowledged once, got: %d", msg.acks)
+			}
+
+			if !reflect.DeepEqual(scans, tc.Expected) {
+				t.Logf("want: %v", tc.Expected)
+				t.Logf("got:  %v", scans)
+				t.Errorf("Scans do not equal")
+			}
+
+			if !reflect.DeepEqual(delays, tc.Delays) {
+				t.Logf("want: %v", tc.Delays)
+				t.Logf("got:  %v", delays)
+				t.Errorf("Delays do not equal")
+			}
+		})
+	}
+}
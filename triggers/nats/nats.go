@@ -0,0 +1,256 @@
+package nats
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/rs/zerolog"
+
+	"github.com/cloudbox/autoscan"
+	"github.com/cloudbox/autoscan/triggers/internal/message"
+)
+
+type Config struct {
+	URL         string             `yaml:"url"`
+	Subject     string             `yaml:"subject"`
+	Stream      string             `yaml:"stream"`
+	Durable     string             `yaml:"durable"`
+	Username    string             `yaml:"username"`
+	Password    string             `yaml:"password"`
+	Token       string             `yaml:"token"`
+	Credentials string             `yaml:"credentials"`
+	Priority    int                `yaml:"priority"`
+	Dirname     bool               `yaml:"dirname"`
+	Verbosity   string             `yaml:"verbosity"`
+	Rewrite     []autoscan.Rewrite `yaml:"rewrite"`
+	Include     []string           `yaml:"include"`
+	Exclude     []string           `yaml:"exclude"`
+}
+
+const (
+	defaultDurable = "autoscan"
+
+	minBackoff = time.Second
+	maxBackoff = time.Minute
+)
+
+type daemon struct {
+	message.Handler
+	callback autoscan.ProcessorFunc
+	options  []nats.Option
+	url      string
+	subject  string
+	stream   string
+	durable  string
+	log      zerolog.Logger
+}
+
+// New creates an autoscan-compatible Trigger which subscribes to a NATS subject.
+// When a JetStream stream is configured, messages are only acknowledged once the scans are stored,
+// and redelivered when they could not be stored.
+// Without a stream, core NATS delivers messages at most once.
+func New(c Config) (autoscan.Trigger, error) {
+	l := autoscan.GetLogger(c.Verbosity).With().
+		Str("trigger", "nats").
+		Str("subject", c.Subject).
+		Logger()
+
+	if c.URL == "" || c.Subject == "" {
+		return nil, fmt.Errorf("nats url and subject are required: %w", autoscan.ErrFatal)
+	}
+
+	rewriter, err := autoscan.NewRewriter(c.Rewrite)
+	if err != nil {
+		return nil, err
+	}
+
+	filterer, err := autoscan.NewFilterer(c.Include, c.Exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	durable := c.Durable
+	if durable == "" {
+		durable = defaultDurable
+	}
+
+	options := []nats.Option{
+		nats.Name("autoscan"),
+		nats.MaxReconnects(-1),
+		nats.CustomReconnectDelay(backoff),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			l.Warn().Err(err).Msg("Disconnected, reconnecting")
+		}),
+		nats.ReconnectHandler(func(conn *nats.Conn) {
+			l.Info().Str("url", conn.ConnectedUrl()).Msg("Reconnected")
+		}),
+	}
+
+	switch {
+	case c.Credentials != "":
+		options = append(options, nats.UserCredentials(c.Credentials))
+	case c.Token != "":
+		options = append(options, nats.Token(c.Token))
+	case c.Username != "":
+		options = append(options, nats.UserInfo(c.Username, c.Password))
+	}
+
+	trigger := func(callback autoscan.ProcessorFunc) {
+		d := daemon{
+			Handler: message.Handler{
+				Priority: c.Priority,
+				Dirname:  c.Dirname,
+				Rewrite:  rewriter,
+				Allowed:  filterer,
+			},
+			callback: callback,
+			options:  options,
+			url:      c.URL,
+			subject:  c.Subject,
+			stream:   c.Stream,
+			durable:  durable,
+			log:      l,
+		}
+
+		if d.stream == "" {
+			l.Warn().Msg("No stream configured, messages are lost when the scans cannot be stored")
+		}
+
+		d.subscribe()
+	}
+
+	return trigger, nil
+}
+
+// backoff returns the exponential delay before the given reconnection attempt.
+func backoff(attempts int) time.Duration {
+	delay := minBackoff
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+
+	return delay
+}
+
+// subscribe connects to the server and subscribes to the subject, retrying until it succeeds.
+// Once subscribed, the NATS client takes care of reconnecting and resubscribing.
+func (d *daemon) subscribe() {
+	for attempt := 1; ; attempt++ {
+		err := d.trySubscribe()
+		if err == nil {
+			return
+		}
+
+		delay := backoff(attempt)
+		d.log.Error().
+			Err(err).
+			Dur("retry", delay).
+			Msg("Failed subscribing")
+
+		time.Sleep(delay)
+	}
+}
+
+func (d *daemon) trySubscribe() error {
+	conn, err := nats.Connect(d.url, d.options...)
+	if err != nil {
+		return fmt.Errorf("connect: %w", err)
+	}
+
+	if d.stream == "" {
+		// core NATS does not support acknowledgements
+		if _, err := conn.Subscribe(d.subject, d.handleMessage); err != nil {
+			conn.Close()
+			return fmt.Errorf("subscribe: %w", err)
+		}
+
+		d.log.Info().Msg("Subscribed")
+		return nil
+	}
+
+	js, err := conn.JetStream()
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("jetstream: %w", err)
+	}
+
+	_, err = js.Subscribe(d.subject, d.handleMessage,
+		nats.BindStream(d.stream),
+		nats.Durable(d.durable),
+		nats.ManualAck(),
+	)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("subscribe: %v: %w", d.stream, err)
+	}
+
+	d.log.Info().
+		Str("stream", d.stream).
+		Str("durable", d.durable).
+		Msg("Subscribed")
+	return nil
+}
+
+// acker is implemented by *nats.Msg.
+type acker interface {
+	Ack(opts ...nats.AckOpt) error
+	NakWithDelay(delay time.Duration, opts ...nats.AckOpt) error
+	Term(opts ...nats.AckOpt) error
+}
+
+func (d *daemon) handleMessage(msg *nats.Msg) {
+	d.handle(msg.Data, msg)
+}
+
+func (d *daemon) handle(data []byte, msg acker) {
+	m, scans, err := d.Scans(data)
+	if err != nil {
+		// invalid messages will never succeed, remove them from the stream
+		d.log.Error().
+			Err(err).
+			Str("data", string(data)).
+			Msg("Failed decoding message")
+		d.ack(msg.Term)
+		return
+	}
+
+	if len(scans) > 0 {
+		if err := d.callback(scans...); err != nil {
+			// redeliver the message later
+			d.log.Error().
+				Err(err).
+				Msg("Processor could not process scans")
+			d.ack(func(opts ...nats.AckOpt) error {
+				return msg.NakWithDelay(maxBackoff, opts...)
+			})
+			return
+		}
+	}
+
+	d.ack(msg.Ack)
+
+	for _, scan := range scans {
+		d.log.Info().
+			Str("path", scan.Folder).
+			Str("event", m.Event).
+			Msg("Scan moved to processor")
+	}
+}
+
+// ack acknowledges a JetStream message, core NATS messages cannot be acknowledged.
+func (d *daemon) ack(fn func(opts ...nats.AckOpt) error) {
+	if d.stream == "" {
+		return
+	}
+
+	if err := fn(); err != nil {
+		d.log.Error().
+			Err(err).
+			Msg("Failed acknowledging message")
+	}
+}
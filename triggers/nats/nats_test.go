@@ -0,0 +1,123 @@
+package nats
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/rs/zerolog"
+
+	"github.com/cloudbox/autoscan"
+	"github.com/cloudbox/autoscan/triggers/internal/message"
+)
+
+type fakeMessage struct {
+	replies []string
+}
+
+func (m *fakeMessage) Ack(...nats.AckOpt) error {
+	m.replies = append(m.replies, "ack")
+	return nil
+}
+
+func (m *fakeMessage) NakWithDelay(time.Duration, ...nats.AckOpt) error {
+	m.replies = append(m.replies, "nak")
+	return nil
+}
+
+func (m *fakeMessage) Term(...nats.AckOpt) error {
+	m.replies = append(m.replies, "term")
+	return nil
+}
+
+func TestHandle(t *testing.T) {
+	type Test struct {
+		Name     string
+		Stream   string
+		Payload  string
+		Err      error
+		Expected []autoscan.Scan
+		Replies  []string
+	}
+
+	var testCases = []Test{
+		{
+			Name:    "Acknowledges stored scans",
+			Stream:  "DOWNLOADS",
+			Payload: `{"paths": ["/downloads/Movies/Interstellar (2014)"]}`,
+			Expected: []autoscan.Scan{
+				{Folder: "/mnt/unionfs/Media/Movies/Interstellar (2014)", Priority: 2},
+			},
+			Replies: []string{"ack"},
+		},
+		{
+			Name:    "Redelivers when the processor fails",
+			Stream:  "DOWNLOADS",
+			Payload: `{"paths": ["/downloads/Movies/Interstellar (2014)"]}`,
+			Err:     errors.New("database is locked"),
+			Replies: []string{"nak"},
+		},
+		{
+			Name:    "Terminates invalid messages",
+			Stream:  "DOWNLOADS",
+			Payload: `{"paths": `,
+			Replies: []string{"term"},
+		},
+		{
+			Name:    "Core NATS messages are not acknowledged",
+			Payload: `{"paths": ["/downloads/Movies/Interstellar (2014)"]}`,
+			Err:     errors.New("database is locked"),
+		},
+	}
+
+	rewriter, _ := autoscan.NewRewriter([]autoscan.Rewrite{{
+		From: "^/downloads/",
+		To:   "/mnt/unionfs/Media/",
+	}})
+
+	filterer, _ := autoscan.NewFilterer(nil, nil)
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var scans []autoscan.Scan
+
+			d := &daemon{
+				Handler: message.Handler{
+					Priority: 2,
+					Rewrite:  rewriter,
+					Allowed:  filterer,
+				},
+				callback: func(s ...autoscan.Scan) error {
+					if tc.Err != nil {
+						return tc.Err
+					}
+
+					for _, scan := range s {
+						scan.Time = time.Time{}
+						scans = append(scans, scan)
+					}
+					return nil
+				},
+				stream: tc.Stream,
+				log:    zerolog.Nop(),
+			}
+
+			msg := &fakeMessage{}
+			d.handle([]byte(tc.Payload), msg)
+
+			if !reflect.DeepEqual(scans, tc.Expected) {
+				t.Logf("want: %v", tc.Expected)
+				t.Logf("got:  %v", scans)
+				t.Errorf("Scans do not equal")
+			}
+
+			if !reflect.DeepEqual(msg.replies, tc.Replies) {
+				t.Logf("want: %v", tc.Replies)
+				t.Logf("got:  %v", msg.replies)
+				t.Errorf("Replies do not equal")
+			}
+		})
+	}
+}
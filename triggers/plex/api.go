@@ -0,0 +1,106 @@
+package plex
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+
+	"github.com/cloudbox/autoscan"
+)
+
+type apiClient struct {
+	client  *http.Client
+	baseURL string
+	token   string
+}
+
+func newAPIClient(baseURL string, token string) *apiClient {
+	return &apiClient{
+		client:  &http.Client{},
+		baseURL: baseURL,
+		token:   token,
+	}
+}
+
+func (c apiClient) do(req *http.Request) (*http.Response, error) {
+	req.Header.Set("X-Plex-Token", c.token)
+	req.Header.Set("Accept", "application/json") // Force JSON Response.
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		return res, nil
+	}
+
+	// statusCode not in the 2xx range, close response
+	res.Body.Close()
+
+	switch res.StatusCode {
+	case 401:
+		return nil, fmt.Errorf("invalid plex token: %s", res.Status)
+	default:
+		return nil, fmt.Errorf("%s", res.Status)
+	}
+}
+
+// Folders returns the folders of the files and locations of a metadata item.
+func (c apiClient) Folders(ratingKey string) ([]string, error) {
+	reqURL := autoscan.JoinURL(c.baseURL, "library", "metadata", ratingKey)
+	req, err := http.NewRequest("GET", reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed creating metadata request: %w", err)
+	}
+
+	res, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("metadata: %w", err)
+	}
+
+	defer res.Body.Close()
+
+	type Response struct {
+		MediaContainer struct {
+			Metadata []struct {
+				Media []struct {
+					Part []struct {
+						File string `json:"file"`
+					} `json:"Part"`
+				} `json:"Media"`
+				Location []struct {
+					Path string `json:"path"`
+				} `json:"Location"`
+			} `json:"Metadata"`
+		} `json:"MediaContainer"`
+	}
+
+	resp := new(Response)
+	if err := json.NewDecoder(res.Body).Decode(resp); err != nil {
+		return nil, fmt.Errorf("failed decoding metadata response: %w", err)
+	}
+
+	// process response
+	folders := make([]string, 0)
+	for _, item := range resp.MediaContainer.Metadata {
+		// movies and episodes
+		for _, media := range item.Media {
+			for _, part := range media.Part {
+				if part.File != "" {
+					folders = append(folders, path.Dir(part.File))
+				}
+			}
+		}
+
+		// shows, seasons and artists
+		for _, location := range item.Location {
+			if location.Path != "" {
+				folders = append(folders, location.Path)
+			}
+		}
+	}
+
+	return folders, nil
+}
@@ -0,0 +1,210 @@
+package plex
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/hlog"
+
+	"github.com/cloudbox/autoscan"
+)
+
+type Config struct {
+	Name      string             `yaml:"name"`
+	URL       string             `yaml:"url"`
+	Token     string             `yaml:"token"`
+	Libraries []Library          `yaml:"libraries"`
+	Events    []string           `yaml:"events"`
+	Priority  int                `yaml:"priority"`
+	Rewrite   []autoscan.Rewrite `yaml:"rewrite"`
+	Verbosity string             `yaml:"verbosity"`
+}
+
+// A Library maps a Plex library section to the path which is scanned
+// for its items when the paths cannot be retrieved from the Plex API.
+type Library struct {
+	Section string `yaml:"section"`
+	Path    string `yaml:"path"`
+}
+
+// maximum amount of memory used to parse the multipart form, the remainder (thumbnails) is stored on disk.
+const maxMemory = 1 << 20
+
+// New creates an autoscan-compatible HTTP Trigger for Plex webhooks.
+// Plex webhooks do not include the paths of an item,
+// therefore the paths are retrieved from the Plex API when configured.
+// Otherwise, the path of the library section of the item is scanned.
+func New(c Config) (autoscan.HTTPTrigger, error) {
+	if c.URL == "" && len(c.Libraries) == 0 {
+		return nil, errors.New("plex url or libraries are required to determine item paths")
+	}
+
+	if c.URL != "" && c.Token == "" {
+		return nil, errors.New("plex token is required to retrieve item paths")
+	}
+
+	rewriter, err := autoscan.NewRewriter(c.Rewrite)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(map[string]bool)
+	for _, e := range c.Events {
+		events[strings.ToLower(e)] = true
+	}
+
+	if len(events) == 0 {
+		events["library.new"] = true
+	}
+
+	libraries := make(map[string]string)
+	for _, l := range c.Libraries {
+		libraries[l.Section] = l.Path
+	}
+
+	var api *apiClient
+	if c.URL != "" {
+		api = newAPIClient(c.URL, c.Token)
+	}
+
+	trigger := func(callback autoscan.ProcessorFunc) http.Handler {
+		return handler{
+			callback:  callback,
+			priority:  c.Priority,
+			rewrite:   rewriter,
+			events:    events,
+			libraries: libraries,
+			api:       api,
+		}
+	}
+
+	return trigger, nil
+}
+
+type handler struct {
+	priority  int
+	rewrite   autoscan.Rewriter
+	callback  autoscan.ProcessorFunc
+	events    map[string]bool
+	libraries map[string]string
+	api       *apiClient
+}
+
+type plexEvent struct {
+	Event string `json:"event"`
+
+	Server struct {
+		UUID string `json:"uuid"`
+	} `json:"Server"`
+
+	Metadata struct {
+		RatingKey    string `json:"ratingKey"`
+		Type         string `json:"type"`
+		Title        string `json:"title"`
+		LibraryTitle string `json:"librarySectionTitle"`
+	} `json:"Metadata"`
+}
+
+func (h handler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	var err error
+	rlog := hlog.FromRequest(r)
+
+	err = r.ParseMultipartForm(maxMemory)
+	if err != nil {
+		rlog.Error().Err(err).Msg("Failed parsing multipart form")
+		rw.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	event := new(plexEvent)
+	err = json.Unmarshal([]byte(r.FormValue("payload")), event)
+	if err != nil {
+		rlog.Error().Err(err).Msg("Failed decoding request")
+		rw.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	rlog.Trace().Interface("event", event).Msg("Received JSON body")
+
+	if !h.events[strings.ToLower(event.Event)] {
+		rlog.Debug().Str("event", event.Event).Msg("Ignoring event")
+		rw.WriteHeader(http.StatusOK)
+		return
+	}
+
+	var folders []string
+	switch {
+	case h.api != nil:
+		if event.Metadata.RatingKey == "" {
+			rlog.Error().Msg("Required fields are missing")
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		folders, err = h.api.Folders(event.Metadata.RatingKey)
+		if err != nil {
+			rlog.Error().
+				Err(err).
+				Str("rating_key", event.Metadata.RatingKey).
+				Msg("Failed retrieving item paths")
+			rw.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+	default:
+		// scan the library section of the item
+		if p, ok := h.libraries[event.Metadata.LibraryTitle]; ok {
+			folders = append(folders, p)
+		}
+	}
+
+	unique := make(map[string]bool)
+	scans := make([]autoscan.Scan, 0)
+
+	for _, folder := range folders {
+		folderPath := h.rewrite(folder)
+		if unique[folderPath] {
+			continue
+		}
+
+		// add scan
+		unique[folderPath] = true
+		scans = append(scans, autoscan.Scan{
+			Folder:   folderPath,
+			Priority: h.priority,
+			Time:     now(),
+			// the plex server sending the webhook does not have to scan the item
+			Origin: event.Server.UUID,
+		})
+	}
+
+	if len(scans) == 0 {
+		rlog.Warn().
+			Str("rating_key", event.Metadata.RatingKey).
+			Str("title", event.Metadata.Title).
+			Str("library", event.Metadata.LibraryTitle).
+			Msg("No paths found for item")
+		rw.WriteHeader(http.StatusOK)
+		return
+	}
+
+	err = h.callback(scans...)
+	if err != nil {
+		rlog.Error().Err(err).Msg("Processor could not process scans")
+		rw.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	rw.WriteHeader(http.StatusOK)
+	for _, scan := range scans {
+		rlog.Info().
+			Str("path", scan.Folder).
+			Str("event", event.Event).
+			Msg("Scan moved to processor")
+	}
+}
+
+var now = time.Now
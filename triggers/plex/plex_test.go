@@ -0,0 +1,272 @@
+package plex
+
+import (
+	"bytes"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cloudbox/autoscan"
+)
+
+func TestHandler(t *testing.T) {
+	type Given struct {
+		Config  Config
+		Fixture string
+	}
+
+	type Expected struct {
+		Scans      []autoscan.Scan
+		StatusCode int
+	}
+
+	type Test struct {
+		Name     string
+		Given    Given
+		Expected Expected
+	}
+
+	standardConfig := Config{
+		Name:     "plex",
+		Token:    "token",
+		Priority: 5,
+		Rewrite: []autoscan.Rewrite{{
+			From: "/data/TV/*",
+			To:   "/mnt/unionfs/Media/TV/$1",
+		}},
+	}
+
+	// without the plex api, the library sections are scanned
+	libraryConfig := Config{
+		Name:     "plex",
+		Priority: 5,
+		Libraries: []Library{{
+			Section: "TV Shows",
+			Path:    "/mnt/unionfs/Media/TV",
+		}},
+	}
+
+	currentTime := time.Now()
+	now = func() time.Time {
+		return currentTime
+	}
+
+	// plex api serving the metadata fixtures
+	api := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Plex-Token") != "token" {
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		b, err := os.ReadFile("testdata/metadata_" + path.Base(r.URL.Path) + ".json")
+		if err != nil {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		rw.Header().Set("Content-Type", "application/json")
+		_, _ = rw.Write(b)
+	}))
+	defer api.Close()
+
+	var testCases = []Test{
+		{
+			"Episode with multiple versions",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/library_new.json",
+			},
+			Expected{
+				StatusCode: 200,
+				Scans: []autoscan.Scan{
+					{
+						Folder:   "/mnt/unionfs/Media/TV/Westworld/Season 1",
+						Priority: 5,
+						Time:     currentTime,
+						Origin:   "54664a3d8acc39983675640ec9ce00b70af9cc36",
+					},
+				},
+			},
+		},
+		{
+			"Show location",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/library_new_show.json",
+			},
+			Expected{
+				StatusCode: 200,
+				Scans: []autoscan.Scan{
+					{
+						Folder:   "/mnt/unionfs/Media/TV/Westworld",
+						Priority: 5,
+						Time:     currentTime,
+					},
+				},
+			},
+		},
+		{
+			"Library section without the Plex API",
+			Given{
+				Config:  libraryConfig,
+				Fixture: "testdata/library_new.json",
+			},
+			Expected{
+				StatusCode: 200,
+				Scans: []autoscan.Scan{
+					{
+						Folder:   "/mnt/unionfs/Media/TV",
+						Priority: 5,
+						Time:     currentTime,
+						Origin:   "54664a3d8acc39983675640ec9ce00b70af9cc36",
+					},
+				},
+			},
+		},
+		{
+			"Returns 200 on library sections which are not configured without emitting a scan",
+			Given{
+				Config:  libraryConfig,
+				Fixture: "testdata/library_new_unknown.json",
+			},
+			Expected{
+				StatusCode: 200,
+			},
+		},
+		{
+			"Returns 200 on events which are not configured without emitting a scan",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/media_play.json",
+			},
+			Expected{
+				StatusCode: 200,
+			},
+		},
+		{
+			"Returns bad request on invalid JSON",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/invalid.json",
+			},
+			Expected{
+				StatusCode: 400,
+			},
+		},
+		{
+			"Returns bad request when the rating key is missing",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/missing_key.json",
+			},
+			Expected{
+				StatusCode: 400,
+			},
+		},
+		{
+			"Returns 500 when the item cannot be retrieved",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/library_new_unknown.json",
+			},
+			Expected{
+				StatusCode: 500,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			callback := func(scans ...autoscan.Scan) error {
+				if !reflect.DeepEqual(tc.Expected.Scans, scans) {
+					t.Log(scans)
+					t.Log(tc.Expected.Scans)
+					t.Errorf("Scans do not equal")
+					return errors.New("Scans do not equal")
+				}
+
+				return nil
+			}
+
+			config := tc.Given.Config
+			if config.Token != "" {
+				config.URL = api.URL
+			}
+
+			trigger, err := New(config)
+			if err != nil {
+				t.Fatalf("Could not create Plex Trigger: %v", err)
+			}
+
+			server := httptest.NewServer(trigger(callback))
+			defer server.Close()
+
+			payload, err := os.ReadFile(tc.Given.Fixture)
+			if err != nil {
+				t.Fatalf("Could not open the fixture: %s", tc.Given.Fixture)
+			}
+
+			// plex sends the payload as a multipart form
+			body := new(bytes.Buffer)
+			form := multipart.NewWriter(body)
+			if err := form.WriteField("payload", string(payload)); err != nil {
+				t.Fatalf("Failed creating form: %v", err)
+			}
+			form.Close()
+
+			res, err := http.Post(server.URL, form.FormDataContentType(), body)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+
+			defer res.Body.Close()
+			if res.StatusCode != tc.Expected.StatusCode {
+				t.Errorf("Status codes do not match: %d vs %d", res.StatusCode, tc.Expected.StatusCode)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	type Test struct {
+		Name   string
+		Config Config
+		Err    bool
+	}
+
+	var testCases = []Test{
+		{
+			Name:   "Plex API",
+			Config: Config{URL: "http://localhost:32400", Token: "token"},
+		},
+		{
+			Name:   "Library sections",
+			Config: Config{Libraries: []Library{{Section: "Movies", Path: "/data/Movies"}}},
+		},
+		{
+			Name:   "Neither the Plex API nor library sections",
+			Config: Config{},
+			Err:    true,
+		},
+		{
+			Name:   "Plex API without token",
+			Config: Config{URL: "http://localhost:32400"},
+			Err:    true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			_, err := New(tc.Config)
+			if (err != nil) != tc.Err {
+				t.Errorf("Errors do not match: %v, expected error: %v", err, tc.Err)
+			}
+		})
+	}
+}
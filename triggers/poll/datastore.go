@@ -0,0 +1,94 @@
+package poll
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+
+	"github.com/cloudbox/autoscan/migrate"
+)
+
+// A Datastore keeps the snapshots of roots between polls.
+// Next to the poll trigger, it is used by other triggers which compare snapshots.
+type Datastore struct {
+	*sql.DB
+}
+
+var (
+	//go:embed migrations
+	migrations embed.FS
+)
+
+// NewDatastore migrates the snapshot tables and returns the Datastore.
+func NewDatastore(db *sql.DB, mg *migrate.Migrator) (*Datastore, error) {
+	// migrations
+	if err := mg.Migrate(&migrations, "poll"); err != nil {
+		return nil, fmt.Errorf("migrate: %w", err)
+	}
+
+	return &Datastore{db}, nil
+}
+
+const sqlSelectFolders = `SELECT path, modified, signature FROM poll_folder WHERE root = ?`
+
+// Folders returns the stored snapshot of a root, keyed by folder path.
+func (store *Datastore) Folders(root string) (Snapshot, error) {
+	rows, err := store.Query(sqlSelectFolders, root)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	folders := make(Snapshot)
+	for rows.Next() {
+		f := Folder{}
+		if err := rows.Scan(&f.Path, &f.Modified, &f.Signature); err != nil {
+			return nil, err
+		}
+
+		folders[f.Path] = f
+	}
+
+	return folders, rows.Err()
+}
+
+const sqlUpsertFolder = `
+INSERT INTO poll_folder (root, path, modified, signature)
+VALUES (?, ?, ?, ?)
+ON CONFLICT (root, path) DO UPDATE SET
+	modified = excluded.modified,
+	signature = excluded.signature
+`
+
+const sqlDeleteFolder = `DELETE FROM poll_folder WHERE root = ? AND path = ?`
+
+// Update stores the changed folders and removes the deleted folders of a root.
+func (store *Datastore) Update(root string, changed []Folder, removed []string) error {
+	tx, err := store.Begin()
+	if err != nil {
+		return err
+	}
+
+	for _, f := range changed {
+		if _, err = tx.Exec(sqlUpsertFolder, root, f.Path, f.Modified, f.Signature); err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				panic(rollbackErr)
+			}
+
+			return err
+		}
+	}
+
+	for _, p := range removed {
+		if _, err = tx.Exec(sqlDeleteFolder, root, p); err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				panic(rollbackErr)
+			}
+
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
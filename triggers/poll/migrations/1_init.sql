@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS poll_folder (
+    "root" TEXT NOT NULL,
+    "path" TEXT NOT NULL,
+    "modified" INTEGER NOT NULL,
+    "signature" TEXT NOT NULL,
+    PRIMARY KEY(root, path)
+)
@@ -0,0 +1,3 @@
+-- signatures no longer cover sub-directories, clearing them marks every folder as changed,
+-- so the next walk queues a scan of each root instead of missing changes made around the upgrade
+UPDATE poll_folder SET signature = ''
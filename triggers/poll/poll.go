@@ -0,0 +1,425 @@
+package poll
+
+import (
+	"crypto/sha1"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/cloudbox/autoscan"
+	"github.com/cloudbox/autoscan/migrate"
+)
+
+const (
+	defaultInterval    = 5 * time.Minute
+	defaultConcurrency = 4
+)
+
+type Config struct {
+	Priority    int                `yaml:"priority"`
+	Interval    time.Duration      `yaml:"interval"`
+	Depth       int                `yaml:"depth"`
+	Concurrency int                `yaml:"concurrency"`
+	Verbosity   string             `yaml:"verbosity"`
+	Rewrite     []autoscan.Rewrite `yaml:"rewrite"`
+	Include     []string           `yaml:"include"`
+	Exclude     []string           `yaml:"exclude"`
+	Paths       []struct {
+		Path    string             `yaml:"path"`
+		Depth   int                `yaml:"depth"`
+		Rewrite []autoscan.Rewrite `yaml:"rewrite"`
+		Include []string           `yaml:"include"`
+		Exclude []string           `yaml:"exclude"`
+	} `yaml:"paths"`
+}
+
+type daemon struct {
+	callback    autoscan.ProcessorFunc
+	paths       []path
+	store       *Datastore
+	priority    int
+	interval    time.Duration
+	concurrency int
+	log         zerolog.Logger
+}
+
+type path struct {
+	Path     string
+	Depth    int
+	Rewriter autoscan.Rewriter
+	Allowed  autoscan.Filterer
+}
+
+// A Folder is the state of a single directory as seen during a walk.
+type Folder struct {
+	Path      string
+	Modified  int64
+	Signature string
+}
+
+// New creates an autoscan-compatible Trigger which periodically walks the configured paths
+// and compares the directory tree against the snapshot kept in the datastore.
+func New(c Config, db *sql.DB, mg *migrate.Migrator) (autoscan.Trigger, error) {
+	l := autoscan.GetLogger(c.Verbosity).With().
+		Str("trigger", "poll").
+		Logger()
+
+	store, err := NewDatastore(db, mg)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", err, autoscan.ErrFatal)
+	}
+
+	var paths []path
+	for _, p := range c.Paths {
+		p := p
+
+		rewriter, err := autoscan.NewRewriter(append(p.Rewrite, c.Rewrite...))
+		if err != nil {
+			return nil, err
+		}
+
+		filterer, err := autoscan.NewFilterer(append(p.Include, c.Include...), append(p.Exclude, c.Exclude...))
+		if err != nil {
+			return nil, err
+		}
+
+		depth := c.Depth
+		if p.Depth > 0 {
+			depth = p.Depth
+		}
+
+		paths = append(paths, path{
+			Path:     filepath.Clean(p.Path),
+			Depth:    depth,
+			Rewriter: rewriter,
+			Allowed:  filterer,
+		})
+	}
+
+	interval := c.Interval
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+
+	concurrency := c.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+
+	trigger := func(callback autoscan.ProcessorFunc) {
+		d := daemon{
+			log:         l,
+			callback:    callback,
+			paths:       paths,
+			store:       store,
+			priority:    c.Priority,
+			interval:    interval,
+			concurrency: concurrency,
+		}
+
+		d.worker()
+	}
+
+	return trigger, nil
+}
+
+func (d *daemon) worker() {
+	t := time.NewTicker(d.interval)
+	defer t.Stop()
+
+	for {
+		for _, p := range d.paths {
+			if err := d.poll(p); err != nil {
+				d.log.Error().
+					Err(err).
+					Str("path", p.Path).
+					Msg("Failed polling path")
+			}
+		}
+
+		<-t.C
+	}
+}
+
+func (d *daemon) poll(p path) error {
+	l := d.log.With().Str("path", p.Path).Logger()
+
+	l.Trace().Msg("Walking path")
+	start := time.Now()
+
+	// walk path
+	current, err := Walk(p.Path, p.Depth, d.concurrency)
+	if err != nil {
+		return fmt.Errorf("walk: %w", err)
+	}
+
+	// retrieve previous snapshot
+	previous, err := d.store.Folders(p.Path)
+	if err != nil {
+		return fmt.Errorf("retrieving snapshot: %v: %w", err, autoscan.ErrFatal)
+	}
+
+	// an unmounted path is an empty directory, which would be seen as fully deleted
+	if len(previous) > 1 && unmounted(p.Path, current) {
+		l.Warn().Msg("Path is empty, skipping walk until it is mounted")
+		return nil
+	}
+
+	// determine differences
+	changes := Compare(previous, current)
+
+	l.Trace().
+		Int("folders", len(current)).
+		Int("created", len(changes.Created)).
+		Int("changed", len(changes.Changed)).
+		Int("deleted", len(changes.Removed)).
+		Msgf("Walk finished in %s", time.Since(start))
+
+	// the first walk of a path only populates the snapshot
+	if len(previous) == 0 {
+		if err := d.store.Update(p.Path, changes.Upserts, changes.Removed); err != nil {
+			return fmt.Errorf("storing snapshot: %v: %w", err, autoscan.ErrFatal)
+		}
+
+		l.Info().
+			Int("folders", len(current)).
+			Msg("Initial snapshot created")
+		return nil
+	}
+
+	// translate changes to scans
+	scans := make([]autoscan.Scan, 0)
+	seen := make(map[string]bool)
+
+	for _, folderPath := range RootFolders(changes.Paths()) {
+		// rewrite path
+		rewritten := p.Rewriter(folderPath)
+		if seen[rewritten] {
+			continue
+		}
+		seen[rewritten] = true
+
+		// is this path allowed?
+		if !p.Allowed(rewritten) {
+			continue
+		}
+
+		scans = append(scans, autoscan.Scan{
+			Folder:   filepath.Clean(rewritten),
+			Priority: d.priority,
+			Time:     time.Now(),
+		})
+	}
+
+	// move scans to processor, the changes are seen again on the next walk when this fails
+	if len(scans) > 0 {
+		if err := d.callback(scans...); err != nil {
+			return fmt.Errorf("moving scans to processor: %v: %w", err, autoscan.ErrFatal)
+		}
+	}
+
+	// store new snapshot once the scans are stored
+	if err := d.store.Update(p.Path, changes.Upserts, changes.Removed); err != nil {
+		return fmt.Errorf("storing snapshot: %v: %w", err, autoscan.ErrFatal)
+	}
+
+	for _, scan := range scans {
+		l.Info().
+			Str("scan", scan.Folder).
+			Msg("Scan moved to processor")
+	}
+
+	return nil
+}
+
+// emptySignature is the signature of a folder without files.
+var emptySignature = hex.EncodeToString(sha1.New().Sum(nil))
+
+// unmounted returns whether the walk of root found a single folder without any entries,
+// as is the case for the mount point of an unmounted file system.
+func unmounted(root string, current Snapshot) bool {
+	f, ok := current[root]
+	if !ok || len(current) != 1 || f.Signature != emptySignature {
+		return false
+	}
+
+	entries, err := os.ReadDir(root)
+	return err == nil && len(entries) == 0
+}
+
+// A Snapshot holds the state of every directory below a root, keyed by path.
+type Snapshot map[string]Folder
+
+// Walk reads the directory tree of root level by level, reading at most concurrency directories at once.
+// A depth of 0 walks the entire tree.
+func Walk(root string, depth int, concurrency int) (Snapshot, error) {
+	// the root must be readable, otherwise an unmounted path would be seen as fully deleted
+	if _, err := os.Stat(root); err != nil {
+		return nil, err
+	}
+
+	snapshot := make(Snapshot)
+	mtx := &sync.Mutex{}
+
+	level := []string{root}
+	for current := 0; len(level) > 0; current++ {
+		next := make([]string, 0)
+
+		g := new(errgroup.Group)
+		g.SetLimit(concurrency)
+
+		for _, dir := range level {
+			dir := dir
+			g.Go(func() error {
+				f, subdirs, err := readFolder(dir)
+				switch {
+				case errors.Is(err, fs.ErrNotExist) && dir != root:
+					// removed during the walk
+					return nil
+				case err != nil:
+					return fmt.Errorf("read folder: %v: %w", dir, err)
+				}
+
+				mtx.Lock()
+				defer mtx.Unlock()
+
+				snapshot[dir] = *f
+				if depth <= 0 || current < depth {
+					next = append(next, subdirs...)
+				}
+
+				return nil
+			})
+		}
+
+		if err := g.Wait(); err != nil {
+			return nil, err
+		}
+
+		level = next
+	}
+
+	return snapshot, nil
+}
+
+// readFolder returns the state of a directory along with the paths of its sub-directories.
+// The signature covers the name, size and modification time of the files within the directory.
+// Sub-directories are left out, as they are tracked as folders of their own.
+func readFolder(dir string) (*Folder, []string, error) {
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	h := sha1.New()
+	subdirs := make([]string, 0)
+
+	for _, e := range entries {
+		if e.IsDir() {
+			subdirs = append(subdirs, filepath.Join(dir, e.Name()))
+			continue
+		}
+
+		info, err := e.Info()
+		if err != nil {
+			// removed during the walk
+			continue
+		}
+
+		_, _ = fmt.Fprintf(h, "f:%s:%d:%d\n", e.Name(), info.Size(), info.ModTime().UnixNano())
+	}
+
+	return &Folder{
+		Path:      dir,
+		Modified:  fi.ModTime().UnixNano(),
+		Signature: hex.EncodeToString(h.Sum(nil)),
+	}, subdirs, nil
+}
+
+// Changes describes the differences between two snapshots.
+type Changes struct {
+	Created []string
+	Changed []string
+	Removed []string
+	Upserts []Folder
+}
+
+// Paths returns all created, changed and removed folders.
+func (c Changes) Paths() []string {
+	paths := make([]string, 0, len(c.Created)+len(c.Changed)+len(c.Removed))
+	paths = append(paths, c.Created...)
+	paths = append(paths, c.Changed...)
+	paths = append(paths, c.Removed...)
+	return paths
+}
+
+// Compare determines which folders were created, changed or removed between two snapshots.
+// A folder only changed when its files changed, a created or removed sub-directory does not change its parent.
+func Compare(previous Snapshot, current Snapshot) Changes {
+	c := Changes{
+		Created: make([]string, 0),
+		Changed: make([]string, 0),
+		Removed: make([]string, 0),
+		Upserts: make([]Folder, 0),
+	}
+
+	for p, f := range current {
+		old, ok := previous[p]
+		switch {
+		case !ok:
+			c.Created = append(c.Created, p)
+			c.Upserts = append(c.Upserts, f)
+		case old.Signature != f.Signature:
+			c.Changed = append(c.Changed, p)
+			c.Upserts = append(c.Upserts, f)
+		case old.Modified != f.Modified:
+			c.Upserts = append(c.Upserts, f)
+		}
+	}
+
+	for p := range previous {
+		if _, ok := current[p]; !ok {
+			c.Removed = append(c.Removed, p)
+		}
+	}
+
+	return c
+}
+
+// RootFolders removes every path which has an ancestor within the given paths.
+func RootFolders(paths []string) []string {
+	sort.Strings(paths)
+
+	roots := make([]string, 0)
+	for _, p := range paths {
+		covered := false
+		for _, r := range roots {
+			if p == r || strings.HasPrefix(p, r+string(filepath.Separator)) {
+				covered = true
+				break
+			}
+		}
+
+		if !covered {
+			roots = append(roots, p)
+		}
+	}
+
+	return roots
+}
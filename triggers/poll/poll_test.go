@@ -0,0 +1,312 @@
+package poll
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"github.com/cloudbox/autoscan"
+	"github.com/cloudbox/autoscan/migrate"
+
+	// sqlite3 driver
+	_ "modernc.org/sqlite"
+)
+
+func getDatastore(t *testing.T) *Datastore {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every connection opens a separate in-memory database
+	db.SetMaxOpenConns(1)
+
+	mg, err := migrate.New(db, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := NewDatastore(db, mg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return store
+}
+
+func TestRootFolders(t *testing.T) {
+	type Test struct {
+		Name     string
+		Paths    []string
+		Expected []string
+	}
+
+	var testCases = []Test{
+		{
+			Name:     "Removes nested folders",
+			Paths:    []string{"/TV/Westworld/Season 1", "/TV/Westworld", "/TV/Westworld/Season 2"},
+			Expected: []string{"/TV/Westworld"},
+		},
+		{
+			Name:     "Keeps siblings with a common prefix",
+			Paths:    []string{"/TV/Westworld (2016)", "/TV/Westworld"},
+			Expected: []string{"/TV/Westworld", "/TV/Westworld (2016)"},
+		},
+		{
+			Name:     "Removes duplicates",
+			Paths:    []string{"/Movies/Interstellar", "/Movies/Interstellar"},
+			Expected: []string{"/Movies/Interstellar"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			roots := RootFolders(tc.Paths)
+			if !reflect.DeepEqual(roots, tc.Expected) {
+				t.Logf("want: %v", tc.Expected)
+				t.Logf("got:  %v", roots)
+				t.Errorf("Root folders do not equal")
+			}
+		})
+	}
+}
+
+func TestWalkAndCompare(t *testing.T) {
+	root := t.TempDir()
+	mkdir := func(p string) {
+		if err := os.MkdirAll(filepath.Join(root, p), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mkdir("Westworld/Season 1")
+	mkdir("Westworld/Season 2")
+
+	previous, err := Walk(root, 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(previous) != 4 {
+		t.Fatalf("Expected 4 folders, got %d", len(previous))
+	}
+
+	// add file and folder, remove folder
+	mkdir("Westworld/Season 3")
+	if err := os.WriteFile(filepath.Join(root, "Westworld/Season 1/S01E01.mkv"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(root, "Westworld/Season 2")); err != nil {
+		t.Fatal(err)
+	}
+
+	current, err := Walk(root, 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := Compare(previous, current)
+	sort.Strings(c.Changed)
+
+	if !reflect.DeepEqual(c.Created, []string{filepath.Join(root, "Westworld/Season 3")}) {
+		t.Errorf("Created folders do not equal: %v", c.Created)
+	}
+
+	// the parent of the created and removed seasons did not change itself
+	if !reflect.DeepEqual(c.Changed, []string{filepath.Join(root, "Westworld/Season 1")}) {
+		t.Errorf("Changed folders do not equal: %v", c.Changed)
+	}
+
+	if !reflect.DeepEqual(c.Removed, []string{filepath.Join(root, "Westworld/Season 2")}) {
+		t.Errorf("Removed folders do not equal: %v", c.Removed)
+	}
+
+	// depth limited walk
+	limited, err := Walk(root, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(limited) != 2 {
+		t.Errorf("Expected 2 folders with a depth of 1, got %d", len(limited))
+	}
+}
+
+func TestNewShowInExistingLibrary(t *testing.T) {
+	root := t.TempDir()
+	mkdir := func(p string) {
+		if err := os.MkdirAll(filepath.Join(root, p), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mkdir("TV/Westworld/Season 1")
+
+	previous, err := Walk(root, 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mkdir("TV/Severance/Season 1")
+	if err := os.WriteFile(filepath.Join(root, "TV/Severance/Season 1/S01E01.mkv"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	current, err := Walk(root, 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	roots := RootFolders(Compare(previous, current).Paths())
+
+	expected := []string{filepath.Join(root, "TV/Severance")}
+	if !reflect.DeepEqual(roots, expected) {
+		t.Logf("want: %v", expected)
+		t.Logf("got:  %v", roots)
+		t.Errorf("Root folders do not equal")
+	}
+}
+
+func TestPoll(t *testing.T) {
+	type Step struct {
+		Name     string
+		Change   func(root string) error
+		Fail     bool
+		Expected []string
+	}
+
+	file := func(p string) func(root string) error {
+		return func(root string) error {
+			if err := os.MkdirAll(filepath.Join(root, filepath.Dir(p)), os.ModePerm); err != nil {
+				return err
+			}
+
+			return os.WriteFile(filepath.Join(root, p), []byte("x"), 0644)
+		}
+	}
+
+	steps := []Step{
+		{
+			Name:   "Initial snapshot",
+			Change: file("TV/Westworld/Season 1/S01E01.mkv"),
+		},
+		{
+			Name:   "Processor fails",
+			Change: file("TV/Westworld/Season 1/S01E02.mkv"),
+			Fail:   true,
+		},
+		{
+			Name:     "Changes are retried",
+			Expected: []string{"TV/Westworld/Season 1"},
+		},
+		{
+			Name: "Unmounted path",
+			Change: func(root string) error {
+				return os.RemoveAll(filepath.Join(root, "TV"))
+			},
+		},
+		{
+			Name:     "Mounted again",
+			Change:   file("TV/Westworld/Season 1/S01E03.mkv"),
+			Expected: []string{"TV/Westworld/Season 1"},
+		},
+	}
+
+	root := t.TempDir()
+	rewriter, _ := autoscan.NewRewriter(nil)
+	filterer, _ := autoscan.NewFilterer(nil, nil)
+
+	var scans []string
+	fail := false
+
+	d := &daemon{
+		callback: func(s ...autoscan.Scan) error {
+			if fail {
+				return errors.New("processor unavailable")
+			}
+
+			for _, scan := range s {
+				rel, _ := filepath.Rel(root, scan.Folder)
+				scans = append(scans, rel)
+			}
+			return nil
+		},
+		store:       getDatastore(t),
+		concurrency: 2,
+		log:         zerolog.Nop(),
+	}
+
+	p := path{Path: root, Rewriter: rewriter, Allowed: filterer}
+
+	for _, step := range steps {
+		if step.Change != nil {
+			if err := step.Change(root); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		scans = nil
+		fail = step.Fail
+
+		err := d.poll(p)
+		if step.Fail != (err != nil) {
+			t.Fatalf("%s: unexpected error: %v", step.Name, err)
+		}
+
+		if !reflect.DeepEqual(scans, step.Expected) {
+			t.Logf("want: %v", step.Expected)
+			t.Logf("got:  %v", scans)
+			t.Errorf("%s: scans do not equal", step.Name)
+		}
+	}
+}
+
+func TestUpgradeScansRoot(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "TV/Westworld/Season 1"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	rewriter, _ := autoscan.NewRewriter(nil)
+	filterer, _ := autoscan.NewFilterer(nil, nil)
+
+	var scans []string
+	d := &daemon{
+		callback: func(s ...autoscan.Scan) error {
+			for _, scan := range s {
+				scans = append(scans, scan.Folder)
+			}
+			return nil
+		},
+		store:       getDatastore(t),
+		concurrency: 2,
+		log:         zerolog.Nop(),
+	}
+
+	p := path{Path: root, Rewriter: rewriter, Allowed: filterer}
+	if err := d.poll(p); err != nil {
+		t.Fatal(err)
+	}
+
+	// snapshots of older versions have their signatures cleared by the migration
+	if _, err := d.store.Exec(`UPDATE poll_folder SET signature = ''`); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.poll(p); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{root}
+	if !reflect.DeepEqual(scans, expected) {
+		t.Logf("want: %v", expected)
+		t.Logf("got:  %v", scans)
+		t.Errorf("Scans do not equal")
+	}
+}
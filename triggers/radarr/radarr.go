@@ -2,6 +2,7 @@ package radarr
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"path"
 	"strings"
@@ -43,16 +44,122 @@ type handler struct {
 	callback autoscan.ProcessorFunc
 }
 
+type movieFile struct {
+	// Path is only included by Radarr v3 and later.
+	Path         string `json:"path"`
+	RelativePath string `json:"relativePath"`
+
+	// PreviousPath is only included in renamed movie files.
+	PreviousPath string `json:"previousPath"`
+}
+
+// folder returns the folder of the movie file,
+// falling back to the relative path within the movie folder.
+func (f movieFile) folder(folderPath string) string {
+	switch {
+	case f.Path != "":
+		return path.Dir(f.Path)
+	case f.RelativePath != "" && folderPath != "":
+		return path.Dir(path.Join(folderPath, f.RelativePath))
+	default:
+		return ""
+	}
+}
+
 type radarrEvent struct {
 	Type string `json:"eventType"`
 
-	File struct {
-		RelativePath string
-	} `json:"movieFile"`
+	File movieFile `json:"movieFile"`
 
 	Movie struct {
 		FolderPath string
 	} `json:"movie"`
+
+	RenamedFiles []movieFile `json:"renamedMovieFiles"`
+
+	// DeletedFiles holds the replaced files of an upgrade,
+	// while it is a boolean on the MovieDelete event.
+	DeletedFiles json.RawMessage `json:"deletedFiles"`
+
+	IsUpgrade bool `json:"isUpgrade"`
+}
+
+// deletedFiles returns the files which were replaced by an upgrade.
+func (e radarrEvent) deletedFiles() ([]movieFile, error) {
+	files := make([]movieFile, 0)
+	if len(e.DeletedFiles) == 0 || e.DeletedFiles[0] != '[' {
+		return files, nil
+	}
+
+	if err := json.Unmarshal(e.DeletedFiles, &files); err != nil {
+		return nil, fmt.Errorf("decoding deleted files: %w", err)
+	}
+
+	return files, nil
+}
+
+// paths returns the folders affected by the event.
+// Nil is returned for events which do not affect any folders.
+func (e radarrEvent) paths() ([]string, error) {
+	folderPath := e.Movie.FolderPath
+	paths := make([]string, 0)
+
+	add := func(p string) {
+		if p != "" {
+			paths = append(paths, p)
+		}
+	}
+
+	switch strings.ToLower(e.Type) {
+	// the file of a MovieFileDelete event might still be in the previous folder of a moved movie
+	case "download", "moviefiledelete":
+		add(e.File.folder(folderPath))
+
+		// the replaced files of an upgrade might have been in another folder
+		deleted, err := e.deletedFiles()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, f := range deleted {
+			add(f.folder(folderPath))
+		}
+
+	case "moviedelete", "movieadded":
+		add(folderPath)
+
+	// scan both the previous and the current folders of the renamed files
+	case "rename":
+		for _, f := range e.RenamedFiles {
+			if f.PreviousPath != "" {
+				add(path.Dir(f.PreviousPath))
+			}
+
+			add(f.folder(folderPath))
+		}
+
+		// older versions of Radarr do not include the renamed files
+		if len(paths) == 0 {
+			add(folderPath)
+		}
+
+	default:
+		return nil, nil
+	}
+
+	// remove duplicates while keeping the order
+	encountered := make(map[string]bool)
+	unique := make([]string, 0, len(paths))
+	for _, p := range paths {
+		if encountered[p] {
+			continue
+		}
+
+		encountered[p] = true
+		unique = append(unique, p)
+	}
+
+	return unique, nil
 }
 
 func (h handler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
@@ -75,45 +182,49 @@ func (h handler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var folderPath string
-
-	if strings.EqualFold(event.Type, "Download") || strings.EqualFold(event.Type, "MovieFileDelete") {
-		if event.File.RelativePath == "" || event.Movie.FolderPath == "" {
-			rlog.Error().Msg("Required fields are missing")
-			rw.WriteHeader(http.StatusBadRequest)
-			return
-		}
-
-		folderPath = path.Dir(path.Join(event.Movie.FolderPath, event.File.RelativePath))
+	paths, err := event.paths()
+	if err != nil {
+		rlog.Error().Err(err).Msg("Failed decoding request")
+		rw.WriteHeader(http.StatusBadRequest)
+		return
 	}
 
-	if strings.EqualFold(event.Type, "MovieDelete") || strings.EqualFold(event.Type, "Rename") {
-		if event.Movie.FolderPath == "" {
-			rlog.Error().Msg("Required fields are missing")
-			rw.WriteHeader(http.StatusBadRequest)
-			return
-		}
+	if paths == nil {
+		// Grab, Health and other events without file system changes
+		rlog.Debug().Str("event", event.Type).Msg("Ignoring event")
+		rw.WriteHeader(http.StatusOK)
+		return
+	}
 
-		folderPath = event.Movie.FolderPath
+	if len(paths) == 0 {
+		rlog.Error().Msg("Required fields are missing")
+		rw.WriteHeader(http.StatusBadRequest)
+		return
 	}
 
-	scan := autoscan.Scan{
-		Folder:   h.rewrite(folderPath),
-		Priority: h.priority,
-		Time:     now(),
+	scans := make([]autoscan.Scan, 0, len(paths))
+	for _, folderPath := range paths {
+		scans = append(scans, autoscan.Scan{
+			Folder:   h.rewrite(folderPath),
+			Priority: h.priority,
+			Time:     now(),
+		})
 	}
 
-	err = h.callback(scan)
+	err = h.callback(scans...)
 	if err != nil {
-		rlog.Error().Err(err).Msg("Processor could not process scan")
+		rlog.Error().Err(err).Msg("Processor could not process scans")
 		rw.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	rlog.Info().
-		Str("path", folderPath).
-		Str("event", event.Type).
-		Msg("Scan moved to processor")
+	for _, scan := range scans {
+		rlog.Info().
+			Str("path", scan.Folder).
+			Str("event", event.Type).
+			Bool("upgrade", event.IsUpgrade).
+			Msg("Scan moved to processor")
+	}
 
 	rw.WriteHeader(http.StatusOK)
 }
@@ -38,6 +38,22 @@ func TestHandler(t *testing.T) {
 		}},
 	}
 
+	// movies moved between the regular and the 4K root folder
+	movedConfig := Config{
+		Name:     "radarr",
+		Priority: 5,
+		Rewrite: []autoscan.Rewrite{
+			{
+				From: "/Movies-4K/*",
+				To:   "/mnt/unionfs/Media/Movies-4K/$1",
+			},
+			{
+				From: "/Movies/*",
+				To:   "/mnt/unionfs/Media/Movies/$1",
+			},
+		},
+	}
+
 	currentTime := time.Now()
 	now = func() time.Time {
 		return currentTime
@@ -112,6 +128,119 @@ func TestHandler(t *testing.T) {
 				},
 			},
 		},
+		{
+			"Scans the folders of new and replaced files on upgrade",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/upgrade.json",
+			},
+			Expected{
+				StatusCode: 200,
+				Scans: []autoscan.Scan{
+					{
+						Folder:   "/mnt/unionfs/Media/Movies/Interstellar (2014)",
+						Priority: 5,
+						Time:     currentTime,
+					},
+					{
+						Folder:   "/mnt/unionfs/Media/Movies/Interstellar",
+						Priority: 5,
+						Time:     currentTime,
+					},
+				},
+			},
+		},
+		{
+			"Scans the previous and current folders of a moved movie on Rename",
+			Given{
+				Config:  movedConfig,
+				Fixture: "testdata/rename_moved.json",
+			},
+			Expected{
+				StatusCode: 200,
+				Scans: []autoscan.Scan{
+					{
+						Folder:   "/mnt/unionfs/Media/Movies/Deadpool (2016)",
+						Priority: 5,
+						Time:     currentTime,
+					},
+					{
+						Folder:   "/mnt/unionfs/Media/Movies-4K/Deadpool (2016)",
+						Priority: 5,
+						Time:     currentTime,
+					},
+					{
+						Folder:   "/mnt/unionfs/Media/Movies-4K/Deadpool (2016)/Extras",
+						Priority: 5,
+						Time:     currentTime,
+					},
+				},
+			},
+		},
+		{
+			"Scans the previous folder of a moved movie on MovieFileDelete",
+			Given{
+				Config:  movedConfig,
+				Fixture: "testdata/movie_file_delete_moved.json",
+			},
+			Expected{
+				StatusCode: 200,
+				Scans: []autoscan.Scan{
+					{
+						Folder:   "/mnt/unionfs/Media/Movies/Tenet (2020)",
+						Priority: 5,
+						Time:     currentTime,
+					},
+				},
+			},
+		},
+		{
+			"Scans the movie folder on MovieAdded",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/movie_added.json",
+			},
+			Expected{
+				StatusCode: 200,
+				Scans: []autoscan.Scan{
+					{
+						Folder:   "/mnt/unionfs/Media/Movies/Parasite (2019)",
+						Priority: 5,
+						Time:     currentTime,
+					},
+				},
+			},
+		},
+		{
+			"Returns bad request on malformed deleted files",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/malformed_deleted_files.json",
+			},
+			Expected{
+				StatusCode: 400,
+			},
+		},
+		{
+			"Returns bad request when required fields are missing",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/missing_fields.json",
+			},
+			Expected{
+				StatusCode: 400,
+			},
+		},
+		{
+			"Returns 200 on Grab event without emitting a scan",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/grab.json",
+			},
+			Expected{
+				StatusCode: 200,
+			},
+		},
 		{
 			"Returns bad request on invalid JSON",
 			Given{
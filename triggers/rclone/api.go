@@ -0,0 +1,139 @@
+package rclone
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/cloudbox/autoscan"
+)
+
+type apiClient struct {
+	client   *http.Client
+	baseURL  string
+	username string
+	password string
+}
+
+func newAPIClient(baseURL string, username string, password string) *apiClient {
+	return &apiClient{
+		// recursive listings of large remotes take a while
+		client:   &http.Client{Timeout: 30 * time.Minute},
+		baseURL:  baseURL,
+		username: username,
+		password: password,
+	}
+}
+
+// call executes a command of the remote control and decodes the response into v.
+func (c apiClient) call(command string, params interface{}, v interface{}) error {
+	b, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("failed encoding params: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", autoscan.JoinURL(c.baseURL, command), bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("failed creating request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		// errors of commands are described in the body
+		rcErr := struct {
+			Error string `json:"error"`
+		}{}
+
+		if err := json.NewDecoder(res.Body).Decode(&rcErr); err == nil && rcErr.Error != "" {
+			return fmt.Errorf("%s: %s", res.Status, rcErr.Error)
+		}
+
+		return fmt.Errorf("%s", res.Status)
+	}
+
+	if v == nil {
+		return nil
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed decoding response: %w", err)
+	}
+
+	return nil
+}
+
+type item struct {
+	Path    string    `json:"Path"`
+	Name    string    `json:"Name"`
+	Size    int64     `json:"Size"`
+	ModTime time.Time `json:"ModTime"`
+	IsDir   bool      `json:"IsDir"`
+}
+
+// List recursively lists a directory of a remote.
+// The paths of the items are relative to the directory.
+func (c apiClient) List(fs string, dir string, fastList bool) ([]item, error) {
+	params := map[string]interface{}{
+		"fs":     fs,
+		"remote": dir,
+		"opt": map[string]interface{}{
+			"recurse":    true,
+			"noMimeType": true,
+		},
+	}
+
+	if fastList {
+		params["_config"] = map[string]interface{}{
+			"UseListR": true,
+		}
+	}
+
+	resp := struct {
+		List []item `json:"list"`
+	}{}
+
+	if err := c.call("operations/list", params, &resp); err != nil {
+		return nil, fmt.Errorf("list: %w", err)
+	}
+
+	return resp.List, nil
+}
+
+// Refresh recursively refreshes the directory cache of the VFS for the given directories.
+func (c apiClient) Refresh(fs string, dirs []string) error {
+	params := map[string]interface{}{
+		"recursive": true,
+	}
+
+	if fs != "" {
+		params["fs"] = fs
+	}
+
+	for i, dir := range dirs {
+		key := "dir"
+		if i > 0 {
+			key = fmt.Sprintf("dir%d", i+1)
+		}
+
+		params[key] = dir
+	}
+
+	if err := c.call("vfs/refresh", params, nil); err != nil {
+		return fmt.Errorf("vfs refresh: %w", err)
+	}
+
+	return nil
+}
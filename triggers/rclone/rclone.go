@@ -0,0 +1,308 @@
+package rclone
+
+import (
+	"crypto/sha1"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/cloudbox/autoscan"
+	"github.com/cloudbox/autoscan/migrate"
+	"github.com/cloudbox/autoscan/triggers/poll"
+)
+
+// listing a remote costs API requests of the backend, list conservatively by default
+const defaultInterval = 15 * time.Minute
+
+type Config struct {
+	URL       string             `yaml:"url"`
+	Username  string             `yaml:"username"`
+	Password  string             `yaml:"password"`
+	Remote    string             `yaml:"remote"`
+	Paths     []string           `yaml:"paths"`
+	Interval  time.Duration      `yaml:"interval"`
+	FastList  bool               `yaml:"fast-list"`
+	Refresh   bool               `yaml:"refresh"`
+	VFS       string             `yaml:"vfs"`
+	Priority  int                `yaml:"priority"`
+	Verbosity string             `yaml:"verbosity"`
+	Rewrite   []autoscan.Rewrite `yaml:"rewrite"`
+	Include   []string           `yaml:"include"`
+	Exclude   []string           `yaml:"exclude"`
+}
+
+type daemon struct {
+	callback autoscan.ProcessorFunc
+	api      *apiClient
+	store    *poll.Datastore
+	remote   string
+	paths    []string
+	interval time.Duration
+	fastList bool
+	refresh  bool
+	vfs      string
+	priority int
+	rewrite  autoscan.Rewriter
+	allowed  autoscan.Filterer
+	log      zerolog.Logger
+}
+
+// New creates an autoscan-compatible Trigger which periodically lists the configured paths
+// of a remote through the remote control of rclone, and compares the listing against the snapshot kept in the datastore.
+// Any backend supported by rclone can be polled.
+// The snapshots are compared and stored like those of the poll trigger.
+func New(c Config, db *sql.DB, mg *migrate.Migrator) (autoscan.Trigger, error) {
+	l := autoscan.GetLogger(c.Verbosity).With().
+		Str("trigger", "rclone").
+		Str("remote", c.Remote).
+		Logger()
+
+	if c.URL == "" || c.Remote == "" {
+		return nil, fmt.Errorf("url and remote are required: %w", autoscan.ErrFatal)
+	}
+
+	// listing the entire remote is expensive, the paths must be chosen explicitly
+	if len(c.Paths) == 0 {
+		return nil, fmt.Errorf("paths are required: %w", autoscan.ErrFatal)
+	}
+
+	rewriter, err := autoscan.NewRewriter(c.Rewrite)
+	if err != nil {
+		return nil, err
+	}
+
+	filterer, err := autoscan.NewFilterer(c.Include, c.Exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := poll.NewDatastore(db, mg)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", err, autoscan.ErrFatal)
+	}
+
+	paths := make([]string, 0, len(c.Paths))
+	for _, p := range c.Paths {
+		paths = append(paths, cleanPath(p))
+	}
+
+	interval := c.Interval
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+
+	trigger := func(callback autoscan.ProcessorFunc) {
+		d := daemon{
+			callback: callback,
+			api:      newAPIClient(c.URL, c.Username, c.Password),
+			store:    store,
+			remote:   c.Remote,
+			paths:    paths,
+			interval: interval,
+			fastList: c.FastList,
+			refresh:  c.Refresh,
+			vfs:      c.VFS,
+			priority: c.Priority,
+			rewrite:  rewriter,
+			allowed:  filterer,
+			log:      l,
+		}
+
+		d.worker()
+	}
+
+	return trigger, nil
+}
+
+func (d *daemon) worker() {
+	t := time.NewTicker(d.interval)
+	defer t.Stop()
+
+	for {
+		for _, p := range d.paths {
+			if err := d.poll(p); err != nil {
+				d.log.Error().
+					Err(err).
+					Str("path", p).
+					Msg("Failed polling path")
+			}
+		}
+
+		<-t.C
+	}
+}
+
+func (d *daemon) poll(p string) error {
+	l := d.log.With().Str("path", p).Logger()
+
+	l.Trace().Msg("Listing path")
+	start := time.Now()
+
+	items, err := d.api.List(d.remote, strings.TrimPrefix(p, "/"), d.fastList)
+	if err != nil {
+		return err
+	}
+
+	current := newSnapshot(p, items)
+
+	// retrieve previous snapshot
+	root := d.remote + p
+	previous, err := d.store.Folders(root)
+	if err != nil {
+		return fmt.Errorf("retrieving snapshot: %v: %w", err, autoscan.ErrFatal)
+	}
+
+	// determine differences
+	changes := poll.Compare(previous, current)
+
+	l.Trace().
+		Int("folders", len(current)).
+		Int("created", len(changes.Created)).
+		Int("changed", len(changes.Changed)).
+		Int("deleted", len(changes.Removed)).
+		Msgf("Listing finished in %s", time.Since(start))
+
+	// the first listing of a path only populates the snapshot
+	if len(previous) == 0 {
+		if err := d.store.Update(root, changes.Upserts, changes.Removed); err != nil {
+			return fmt.Errorf("storing snapshot: %v: %w", err, autoscan.ErrFatal)
+		}
+
+		l.Info().
+			Int("folders", len(current)).
+			Msg("Initial snapshot created")
+		return nil
+	}
+
+	folders := poll.RootFolders(changes.Paths())
+	if d.refresh && len(folders) > 0 {
+		d.refreshFolders(folders, current)
+	}
+
+	// translate changes to scans
+	scans := make([]autoscan.Scan, 0)
+	seen := make(map[string]bool)
+
+	for _, folderPath := range folders {
+		// rewrite path
+		rewritten := d.rewrite(folderPath)
+		if seen[rewritten] {
+			continue
+		}
+		seen[rewritten] = true
+
+		// is this path allowed?
+		if !d.allowed(rewritten) {
+			continue
+		}
+
+		scans = append(scans, autoscan.Scan{
+			Folder:   path.Clean(rewritten),
+			Priority: d.priority,
+			Time:     now(),
+		})
+	}
+
+	// move scans to processor
+	if len(scans) > 0 {
+		if err := d.callback(scans...); err != nil {
+			return fmt.Errorf("moving scans to processor: %v: %w", err, autoscan.ErrFatal)
+		}
+	}
+
+	// store new snapshot once the scans are stored, so a failed callback is retried by the next listing
+	if err := d.store.Update(root, changes.Upserts, changes.Removed); err != nil {
+		return fmt.Errorf("storing snapshot: %v: %w", err, autoscan.ErrFatal)
+	}
+
+	for _, scan := range scans {
+		l.Info().
+			Str("scan", scan.Folder).
+			Msg("Scan moved to processor")
+	}
+
+	return nil
+}
+
+// refreshFolders refreshes the VFS directory cache of the changed folders,
+// so the mount shows the changes before the targets scan it.
+// Removed folders cannot be refreshed, instead their parent folder is refreshed.
+func (d *daemon) refreshFolders(folders []string, current poll.Snapshot) {
+	dirs := make([]string, 0, len(folders))
+	seen := make(map[string]bool)
+
+	for _, f := range folders {
+		if _, ok := current[f]; !ok {
+			f = path.Dir(f)
+		}
+
+		dir := strings.TrimPrefix(f, "/")
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+
+	if err := d.api.Refresh(d.vfs, dirs); err != nil {
+		// the VFS will pick up the changes once its cache expires
+		d.log.Warn().
+			Err(err).
+			Strs("folders", dirs).
+			Msg("Failed refreshing VFS")
+	}
+}
+
+// newSnapshot creates a snapshot of a recursive listing of the directory dir.
+// Like the poll trigger, the signature of a folder covers the name, size and modification time of its files,
+// sub-directories are tracked as folders of their own.
+// Not every backend keeps the modification time of directories, therefore it is left out.
+func newSnapshot(dir string, items []item) poll.Snapshot {
+	entries := make(map[string][]string)
+	entries[dir] = []string{}
+
+	for _, i := range items {
+		p := path.Join(dir, i.Path)
+
+		if i.IsDir {
+			if _, ok := entries[p]; !ok {
+				entries[p] = []string{}
+			}
+
+			continue
+		}
+
+		parent := path.Dir(p)
+		entries[parent] = append(entries[parent], fmt.Sprintf("f:%s:%d:%d", i.Name, i.Size, i.ModTime.UnixNano()))
+	}
+
+	s := make(poll.Snapshot, len(entries))
+	for p, e := range entries {
+		// the order of a listing is not guaranteed
+		sort.Strings(e)
+
+		h := sha1.New()
+		for _, entry := range e {
+			_, _ = fmt.Fprintln(h, entry)
+		}
+
+		s[p] = poll.Folder{
+			Path:      p,
+			Signature: hex.EncodeToString(h.Sum(nil)),
+		}
+	}
+
+	return s
+}
+
+// cleanPath returns the path within the remote with a leading slash.
+func cleanPath(p string) string {
+	return path.Clean("/" + p)
+}
+
+var now = time.Now
@@ -0,0 +1,224 @@
+package rclone
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/cloudbox/autoscan"
+	"github.com/cloudbox/autoscan/migrate"
+	"github.com/cloudbox/autoscan/triggers/poll"
+
+	// sqlite3 driver
+	_ "modernc.org/sqlite"
+)
+
+func getDatastore(t *testing.T) *poll.Datastore {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every connection opens a separate in-memory database
+	db.SetMaxOpenConns(1)
+
+	mg, err := migrate.New(db, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := poll.NewDatastore(db, mg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return store
+}
+
+func TestPoll(t *testing.T) {
+	modTime := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	listing := []item{
+		{Path: "Westworld", Name: "Westworld", IsDir: true},
+		{Path: "Westworld/Season 1", Name: "Season 1", IsDir: true},
+		{Path: "Westworld/Season 1/S01E01.mkv", Name: "S01E01.mkv", Size: 100, ModTime: modTime},
+		{Path: "Westworld/Season 2", Name: "Season 2", IsDir: true},
+		{Path: "Chernobyl", Name: "Chernobyl", IsDir: true},
+		{Path: "Chernobyl/Season 1", Name: "Season 1", IsDir: true},
+	}
+
+	var refreshed map[string]interface{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		params := make(map[string]interface{})
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+			t.Fatal(err)
+		}
+
+		switch r.URL.Path {
+		case "/operations/list":
+			if params["fs"] != "gdrive:" || params["remote"] != "Media/TV" {
+				t.Errorf("Unexpected params: %v", params)
+			}
+
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"list": listing})
+		case "/vfs/refresh":
+			refreshed = params
+			_, _ = w.Write([]byte(`{"result": {}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error": "couldn't find method", "status": 404}`))
+		}
+	}))
+	defer server.Close()
+
+	var scans []autoscan.Scan
+	var callbackErr error
+
+	rewriter, _ := autoscan.NewRewriter([]autoscan.Rewrite{{
+		From: "^/Media/",
+		To:   "/mnt/remote/Media/",
+	}})
+
+	filterer, _ := autoscan.NewFilterer(nil, nil)
+
+	d := &daemon{
+		callback: func(s ...autoscan.Scan) error {
+			if callbackErr != nil {
+				return callbackErr
+			}
+
+			scans = s
+			return nil
+		},
+		api:      newAPIClient(server.URL, "", ""),
+		store:    getDatastore(t),
+		remote:   "gdrive:",
+		refresh:  true,
+		priority: 1,
+		rewrite:  rewriter,
+		allowed:  filterer,
+	}
+
+	currentTime := time.Now()
+	now = func() time.Time {
+		return currentTime
+	}
+	defer func() {
+		now = time.Now
+	}()
+
+	// the first listing only creates the snapshot
+	if err := d.poll("/Media/TV"); err != nil {
+		t.Fatal(err)
+	}
+
+	if scans != nil {
+		t.Fatalf("Expected no scans on the first poll, got: %v", scans)
+	}
+
+	// add an episode, remove a season
+	listing = []item{
+		{Path: "Westworld", Name: "Westworld", IsDir: true},
+		{Path: "Westworld/Season 1", Name: "Season 1", IsDir: true},
+		{Path: "Westworld/Season 1/S01E01.mkv", Name: "S01E01.mkv", Size: 100, ModTime: modTime},
+		{Path: "Westworld/Season 1/S01E02.mkv", Name: "S01E02.mkv", Size: 100, ModTime: modTime},
+		{Path: "Westworld/Season 2", Name: "Season 2", IsDir: true},
+		{Path: "Chernobyl", Name: "Chernobyl", IsDir: true},
+	}
+
+	if err := d.poll("/Media/TV"); err != nil {
+		t.Fatal(err)
+	}
+
+	sort.Slice(scans, func(i, j int) bool {
+		return scans[i].Folder < scans[j].Folder
+	})
+
+	// the removed season is scanned instead of its show
+	expected := []autoscan.Scan{
+		{Folder: "/mnt/remote/Media/TV/Chernobyl/Season 1", Priority: 1, Time: currentTime},
+		{Folder: "/mnt/remote/Media/TV/Westworld/Season 1", Priority: 1, Time: currentTime},
+	}
+
+	if !reflect.DeepEqual(scans, expected) {
+		t.Logf("want: %v", expected)
+		t.Logf("got:  %v", scans)
+		t.Errorf("Scans do not equal")
+	}
+
+	expectedRefresh := map[string]interface{}{
+		"recursive": true,
+		"dir":       "Media/TV/Chernobyl",
+		"dir2":      "Media/TV/Westworld/Season 1",
+	}
+
+	if !reflect.DeepEqual(refreshed, expectedRefresh) {
+		t.Logf("want: %v", expectedRefresh)
+		t.Logf("got:  %v", refreshed)
+		t.Errorf("Refreshed folders do not equal")
+	}
+
+	// unchanged listing
+	scans = nil
+	if err := d.poll("/Media/TV"); err != nil {
+		t.Fatal(err)
+	}
+
+	if scans != nil {
+		t.Errorf("Expected no scans for an unchanged listing, got: %v", scans)
+	}
+
+	// a new show does not roll up to the library
+	listing = append(listing,
+		item{Path: "Severance", Name: "Severance", IsDir: true},
+		item{Path: "Severance/Season 1", Name: "Season 1", IsDir: true},
+		item{Path: "Severance/Season 1/S01E01.mkv", Name: "S01E01.mkv", Size: 100, ModTime: modTime},
+	)
+
+	// a failing processor keeps the previous snapshot
+	callbackErr = errors.New("database is locked")
+	if err := d.poll("/Media/TV"); err == nil {
+		t.Fatal("Expected an error when the processor fails")
+	}
+
+	callbackErr = nil
+	if err := d.poll("/Media/TV"); err != nil {
+		t.Fatal(err)
+	}
+
+	expected = []autoscan.Scan{
+		{Folder: "/mnt/remote/Media/TV/Severance", Priority: 1, Time: currentTime},
+	}
+
+	if !reflect.DeepEqual(scans, expected) {
+		t.Logf("want: %v", expected)
+		t.Logf("got:  %v", scans)
+		t.Errorf("Scans of a new show do not equal")
+	}
+}
+
+func TestNewRequiresPaths(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db.SetMaxOpenConns(1)
+
+	mg, err := migrate.New(db, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = New(Config{URL: "http://localhost:5572", Remote: "gdrive:"}, db, mg)
+	if !errors.Is(err, autoscan.ErrFatal) {
+		t.Errorf("Expected a fatal error without paths, got: %v", err)
+	}
+}
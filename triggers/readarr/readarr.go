@@ -43,13 +43,71 @@ type handler struct {
 	callback autoscan.ProcessorFunc
 }
 
+type bookFile struct {
+	Path string `json:"path"`
+
+	// PreviousPath is only included in renamed book files.
+	PreviousPath string `json:"previousPath"`
+}
+
 type readarrEvent struct {
 	Type    string `json:"eventType"`
 	Upgrade bool   `json:"isUpgrade"`
 
-	Files []struct {
-		Path string
-	} `json:"bookFiles"`
+	Files []bookFile `json:"bookFiles"`
+	File  bookFile   `json:"bookFile"`
+
+	RenamedFiles []bookFile `json:"renamedBookFiles"`
+
+	Author struct {
+		Path string `json:"path"`
+	} `json:"author"`
+}
+
+// paths returns the folders affected by the event.
+// Nil is returned for events which do not affect any folders.
+func (e readarrEvent) paths() []string {
+	paths := make([]string, 0)
+
+	addFile := func(filePath string) {
+		if filePath != "" {
+			paths = append(paths, path.Dir(filePath))
+		}
+	}
+
+	switch strings.ToLower(e.Type) {
+	case "download":
+		for _, f := range e.Files {
+			addFile(f.Path)
+		}
+
+	// a book file was deleted or its tags were rewritten
+	case "bookfiledelete", "retag", "bookretag":
+		addFile(e.File.Path)
+
+	// scan both the previous and the current folder
+	case "rename":
+		for _, f := range e.RenamedFiles {
+			addFile(f.PreviousPath)
+			addFile(f.Path)
+		}
+
+		// older versions do not include the renamed book files, scan the folder of the author instead
+		if len(e.RenamedFiles) == 0 && e.Author.Path != "" {
+			paths = append(paths, e.Author.Path)
+		}
+
+	// a book or author was deleted, scan the folder of the author
+	case "bookdelete", "authordelete":
+		if e.Author.Path != "" {
+			paths = append(paths, e.Author.Path)
+		}
+
+	default:
+		return nil
+	}
+
+	return paths
 }
 
 func (h handler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
@@ -72,8 +130,15 @@ func (h handler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	//Only handle test and download. Everything else is ignored.
-	if !strings.EqualFold(event.Type, "Download") || len(event.Files) == 0 {
+	paths := event.paths()
+	if paths == nil {
+		// Grab, Health and other events without file system changes
+		l.Debug().Str("event", event.Type).Msg("Ignoring event")
+		rw.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if len(paths) == 0 {
 		l.Error().Msg("Required fields are missing")
 		rw.WriteHeader(http.StatusBadRequest)
 		return
@@ -82,8 +147,8 @@ func (h handler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 	unique := make(map[string]bool)
 	scans := make([]autoscan.Scan, 0)
 
-	for _, f := range event.Files {
-		folderPath := path.Dir(h.rewrite(f.Path))
+	for _, p := range paths {
+		folderPath := h.rewrite(p)
 		if _, ok := unique[folderPath]; ok {
 			continue
 		}
@@ -105,10 +170,12 @@ func (h handler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 	}
 
 	rw.WriteHeader(http.StatusOK)
-	l.Info().
-		Str("path", scans[0].Folder).
-		Str("event", event.Type).
-		Msg("Scan moved to processor")
+	for _, scan := range scans {
+		l.Info().
+			Str("path", scan.Folder).
+			Str("event", event.Type).
+			Msg("Scan moved to processor")
+	}
 }
 
 var now = time.Now
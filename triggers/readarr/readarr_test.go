@@ -59,6 +59,133 @@ func TestHandler(t *testing.T) {
 				}},
 			},
 		},
+		{
+			"Scans the previous and current folders on Rename",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/rename.json",
+			},
+			Expected{
+				StatusCode: 200,
+				Scans: []autoscan.Scan{
+					{
+						Folder:   "/mnt/unionfs/Media/Books/Brandon Sanderson/The Way of Kings",
+						Priority: 5,
+						Time:     currentTime,
+					},
+					{
+						Folder:   "/mnt/unionfs/Media/Books/Brandon Sanderson/The Way of Kings (2010)",
+						Priority: 5,
+						Time:     currentTime,
+					},
+				},
+			},
+		},
+		{
+			"Scans the author folder on Rename without renamed files",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/rename_author.json",
+			},
+			Expected{
+				StatusCode: 200,
+				Scans: []autoscan.Scan{
+					{
+						Folder:   "/mnt/unionfs/Media/Books/Brandon Sanderson",
+						Priority: 5,
+						Time:     currentTime,
+					},
+				},
+			},
+		},
+		{
+			"Scans the book folder on BookFileDelete",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/book_file_delete.json",
+			},
+			Expected{
+				StatusCode: 200,
+				Scans: []autoscan.Scan{
+					{
+						Folder:   "/mnt/unionfs/Media/Books/Brandon Sanderson/Words of Radiance (2014)",
+						Priority: 5,
+						Time:     currentTime,
+					},
+				},
+			},
+		},
+		{
+			"Scans the book folder on Retag",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/retag.json",
+			},
+			Expected{
+				StatusCode: 200,
+				Scans: []autoscan.Scan{
+					{
+						Folder:   "/mnt/unionfs/Media/Books/Brandon Sanderson/The Way of Kings (2010)",
+						Priority: 5,
+						Time:     currentTime,
+					},
+				},
+			},
+		},
+		{
+			"Scans the author folder on BookDelete",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/book_delete.json",
+			},
+			Expected{
+				StatusCode: 200,
+				Scans: []autoscan.Scan{
+					{
+						Folder:   "/mnt/unionfs/Media/Books/Brandon Sanderson",
+						Priority: 5,
+						Time:     currentTime,
+					},
+				},
+			},
+		},
+		{
+			"Scans the author folder on AuthorDelete",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/author_delete.json",
+			},
+			Expected{
+				StatusCode: 200,
+				Scans: []autoscan.Scan{
+					{
+						Folder:   "/mnt/unionfs/Media/Books/Patrick Rothfuss",
+						Priority: 5,
+						Time:     currentTime,
+					},
+				},
+			},
+		},
+		{
+			"Returns bad request when required fields are missing",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/missing_fields.json",
+			},
+			Expected{
+				StatusCode: 400,
+			},
+		},
+		{
+			"Returns 200 on Grab event without emitting a scan",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/grab.json",
+			},
+			Expected{
+				StatusCode: 200,
+			},
+		},
 		{
 			"Returns bad request on invalid JSON",
 			Given{
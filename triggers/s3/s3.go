@@ -0,0 +1,221 @@
+package s3
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/hlog"
+
+	"github.com/cloudbox/autoscan"
+)
+
+type Config struct {
+	Name      string             `yaml:"name"`
+	Priority  int                `yaml:"priority"`
+	Topics    []string           `yaml:"topics"`
+	Rewrite   []autoscan.Rewrite `yaml:"rewrite"`
+	Verbosity string             `yaml:"verbosity"`
+}
+
+// New creates an autoscan-compatible HTTP Trigger for S3 bucket event notifications,
+// as sent by MinIO webhook targets and AWS SNS HTTP subscriptions.
+// The objects are translated to the path /bucket/key, which should be rewritten to the local path.
+// SNS messages must be signed by AWS, and are limited to the given topics when configured.
+func New(c Config) (autoscan.HTTPTrigger, error) {
+	rewriter, err := autoscan.NewRewriter(c.Rewrite)
+	if err != nil {
+		return nil, err
+	}
+
+	topics := make(map[string]bool)
+	for _, t := range c.Topics {
+		topics[t] = true
+	}
+
+	trigger := func(callback autoscan.ProcessorFunc) http.Handler {
+		return handler{
+			callback: callback,
+			priority: c.Priority,
+			rewrite:  rewriter,
+			topics:   topics,
+			sns:      newSNSVerifier(),
+		}
+	}
+
+	return trigger, nil
+}
+
+type handler struct {
+	priority int
+	rewrite  autoscan.Rewriter
+	callback autoscan.ProcessorFunc
+	topics   map[string]bool
+	sns      *snsVerifier
+}
+
+type s3Event struct {
+	Records []struct {
+		EventName string `json:"eventName"`
+
+		S3 struct {
+			Bucket struct {
+				Name string `json:"name"`
+			} `json:"bucket"`
+
+			Object struct {
+				Key string `json:"key"`
+			} `json:"object"`
+		} `json:"s3"`
+	} `json:"Records"`
+
+	// AWS test event
+	Event string `json:"Event"`
+
+	// AWS SNS envelope
+	snsMessage
+}
+
+func (h handler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	var err error
+	rlog := hlog.FromRequest(r)
+
+	event := new(s3Event)
+	err = json.NewDecoder(r.Body).Decode(event)
+	if err != nil {
+		rlog.Error().Err(err).Msg("Failed decoding request")
+		rw.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if event.Type != "" {
+		if err := h.sns.verify(event.snsMessage); err != nil {
+			rlog.Error().
+				Err(err).
+				Str("topic", event.TopicArn).
+				Msg("Invalid SNS signature")
+			rw.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		if len(h.topics) > 0 && !h.topics[event.TopicArn] {
+			rlog.Error().
+				Str("topic", event.TopicArn).
+				Msg("SNS topic is not allowed")
+			rw.WriteHeader(http.StatusForbidden)
+			return
+		}
+	}
+
+	switch event.Type {
+	case "SubscriptionConfirmation":
+		if err := confirmSubscription(event.snsMessage); err != nil {
+			rlog.Error().
+				Err(err).
+				Str("topic", event.TopicArn).
+				Str("url", event.SubscribeURL).
+				Msg("Failed confirming SNS subscription")
+			rw.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		rlog.Info().
+			Str("topic", event.TopicArn).
+			Msg("Confirmed SNS subscription")
+		rw.WriteHeader(http.StatusOK)
+		return
+
+	case "UnsubscribeConfirmation":
+		rlog.Warn().
+			Str("topic", event.TopicArn).
+			Msg("SNS subscription was removed")
+		rw.WriteHeader(http.StatusOK)
+		return
+
+	case "Notification":
+		// the S3 event is wrapped in the message of SNS notifications
+		message := event.Message
+		event = new(s3Event)
+		err = json.Unmarshal([]byte(message), event)
+		if err != nil {
+			rlog.Error().Err(err).Msg("Failed decoding SNS message")
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	rlog.Trace().Interface("event", event).Msg("Received JSON body")
+
+	if strings.EqualFold(event.Event, "s3:TestEvent") {
+		rlog.Info().Msg("Received test event")
+		rw.WriteHeader(http.StatusOK)
+		return
+	}
+
+	unique := make(map[string]bool)
+	scans := make([]autoscan.Scan, 0)
+
+	for _, record := range event.Records {
+		// AWS omits the s3: prefix
+		name := strings.TrimPrefix(record.EventName, "s3:")
+		if !strings.HasPrefix(name, "ObjectCreated:") && !strings.HasPrefix(name, "ObjectRemoved:") {
+			continue
+		}
+
+		// object keys are URL encoded
+		key, err := url.QueryUnescape(record.S3.Object.Key)
+		if err != nil || key == "" || record.S3.Bucket.Name == "" {
+			rlog.Error().
+				Str("bucket", record.S3.Bucket.Name).
+				Str("key", record.S3.Object.Key).
+				Msg("Invalid object")
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		// keys ending with a slash are folders
+		objectPath := path.Join("/", record.S3.Bucket.Name, key)
+		folderPath := objectPath
+		if !strings.HasSuffix(key, "/") {
+			folderPath = path.Dir(objectPath)
+		}
+
+		folderPath = h.rewrite(folderPath)
+		if unique[folderPath] {
+			continue
+		}
+
+		// add scan
+		unique[folderPath] = true
+		scans = append(scans, autoscan.Scan{
+			Folder:   folderPath,
+			Priority: h.priority,
+			Time:     now(),
+		})
+	}
+
+	if len(scans) == 0 {
+		rlog.Debug().Msg("No objects were created or removed")
+		rw.WriteHeader(http.StatusOK)
+		return
+	}
+
+	err = h.callback(scans...)
+	if err != nil {
+		rlog.Error().Err(err).Msg("Processor could not process scans")
+		rw.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	rw.WriteHeader(http.StatusOK)
+	for _, scan := range scans {
+		rlog.Info().
+			Str("path", scan.Folder).
+			Msg("Scan moved to processor")
+	}
+}
+
+var now = time.Now
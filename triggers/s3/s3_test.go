@@ -0,0 +1,387 @@
+package s3
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudbox/autoscan"
+)
+
+func TestHandler(t *testing.T) {
+	type Given struct {
+		Config  Config
+		Fixture string
+	}
+
+	type Expected struct {
+		Scans      []autoscan.Scan
+		StatusCode int
+	}
+
+	type Test struct {
+		Name     string
+		Given    Given
+		Expected Expected
+	}
+
+	standardConfig := Config{
+		Name:     "s3",
+		Priority: 5,
+		Rewrite: []autoscan.Rewrite{{
+			From: "^/media/",
+			To:   "/mnt/unionfs/Media/",
+		}},
+	}
+
+	currentTime := time.Now()
+	now = func() time.Time {
+		return currentTime
+	}
+
+	var testCases = []Test{
+		{
+			"MinIO ObjectCreated event",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/minio_put.json",
+			},
+			Expected{
+				StatusCode: 200,
+				Scans: []autoscan.Scan{
+					{
+						Folder:   "/mnt/unionfs/Media/Movies/Interstellar (2014)",
+						Priority: 5,
+						Time:     currentTime,
+					},
+				},
+			},
+		},
+		{
+			"AWS ObjectRemoved events without duplicates",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/aws_removed.json",
+			},
+			Expected{
+				StatusCode: 200,
+				Scans: []autoscan.Scan{
+					{
+						Folder:   "/mnt/unionfs/Media/TV/Westworld/Season 1",
+						Priority: 5,
+						Time:     currentTime,
+					},
+					{
+						Folder:   "/mnt/unionfs/Media/TV/Westworld/Season 2",
+						Priority: 5,
+						Time:     currentTime,
+					},
+				},
+			},
+		},
+		{
+			"Returns 200 on other events without emitting a scan",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/access.json",
+			},
+			Expected{
+				StatusCode: 200,
+			},
+		},
+		{
+			"Returns 200 on Test event without emitting a scan",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/test_event.json",
+			},
+			Expected{
+				StatusCode: 200,
+			},
+		},
+		{
+			"Returns bad request on invalid JSON",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/invalid.json",
+			},
+			Expected{
+				StatusCode: 400,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			callback := func(scans ...autoscan.Scan) error {
+				if !reflect.DeepEqual(tc.Expected.Scans, scans) {
+					t.Log(scans)
+					t.Log(tc.Expected.Scans)
+					t.Errorf("Scans do not equal")
+					return errors.New("Scans do not equal")
+				}
+
+				return nil
+			}
+
+			trigger, err := New(tc.Given.Config)
+			if err != nil {
+				t.Fatalf("Could not create S3 Trigger: %v", err)
+			}
+
+			server := httptest.NewServer(trigger(callback))
+			defer server.Close()
+
+			request, err := os.Open(tc.Given.Fixture)
+			if err != nil {
+				t.Fatalf("Could not open the fixture: %s", tc.Given.Fixture)
+			}
+
+			res, err := http.Post(server.URL, "application/json", request)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+
+			defer res.Body.Close()
+			if res.StatusCode != tc.Expected.StatusCode {
+				t.Errorf("Status codes do not match: %d vs %d", res.StatusCode, tc.Expected.StatusCode)
+			}
+		})
+	}
+}
+
+func TestSNS(t *testing.T) {
+	type Test struct {
+		Name       string
+		Fixture    string
+		Topics     []string
+		Modify     func(m *snsMessage)
+		Tamper     func(m *snsMessage)
+		Scans      []autoscan.Scan
+		Confirmed  bool
+		StatusCode int
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "sns.amazonaws.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// sns serving the signing certificate and confirming subscriptions
+	confirmed := false
+	sns := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/SimpleNotificationService.pem":
+			_ = pem.Encode(rw, &pem.Block{Type: "CERTIFICATE", Bytes: der})
+		case r.URL.Query().Get("Action") == "ConfirmSubscription" && r.URL.Query().Get("Token") == "valid":
+			confirmed = true
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer sns.Close()
+
+	snsClient = sns.Client()
+	snsHost = regexp.MustCompile(`^127\.0\.0\.1$`)
+	defer func() {
+		snsClient = &http.Client{Timeout: 30 * time.Second}
+		snsHost = regexp.MustCompile(`^sns\.[a-z0-9-]+\.amazonaws\.com(\.cn)?$`)
+	}()
+
+	currentTime := time.Now()
+	now = func() time.Time {
+		return currentTime
+	}
+
+	var testCases = []Test{
+		{
+			Name:    "Notification",
+			Fixture: "testdata/sns_notification.json",
+			Scans: []autoscan.Scan{
+				{
+					Folder:   "/mnt/unionfs/Media/Movies/Parasite (2019)",
+					Priority: 5,
+					Time:     currentTime,
+				},
+			},
+			StatusCode: 200,
+		},
+		{
+			Name:    "Notification of an allowed topic with signature version 2",
+			Fixture: "testdata/sns_notification.json",
+			Topics:  []string{"arn:aws:sns:eu-west-1:123456789012:autoscan"},
+			Modify: func(m *snsMessage) {
+				m.SignatureVersion = "2"
+			},
+			Scans: []autoscan.Scan{
+				{
+					Folder:   "/mnt/unionfs/Media/Movies/Parasite (2019)",
+					Priority: 5,
+					Time:     currentTime,
+				},
+			},
+			StatusCode: 200,
+		},
+		{
+			Name:       "Notification of another topic",
+			Fixture:    "testdata/sns_notification.json",
+			Topics:     []string{"arn:aws:sns:eu-west-1:123456789012:other"},
+			StatusCode: 403,
+		},
+		{
+			Name:    "Notification with a modified message",
+			Fixture: "testdata/sns_notification.json",
+			Tamper: func(m *snsMessage) {
+				m.Message = `{"Records": []}`
+			},
+			StatusCode: 403,
+		},
+		{
+			Name:    "Certificate outside of SNS",
+			Fixture: "testdata/sns_notification.json",
+			Tamper: func(m *snsMessage) {
+				m.SigningCertURL = "https://localhost/SimpleNotificationService.pem"
+			},
+			StatusCode: 403,
+		},
+		{
+			Name:       "Subscription is confirmed",
+			Fixture:    "testdata/sns_subscription.json",
+			Confirmed:  true,
+			StatusCode: 200,
+		},
+		{
+			Name:    "Subscription which cannot be confirmed",
+			Fixture: "testdata/sns_subscription.json",
+			Modify: func(m *snsMessage) {
+				m.SubscribeURL = strings.Replace(m.SubscribeURL, "Token=valid", "Token=expired", 1)
+			},
+			StatusCode: 500,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			confirmed = false
+
+			b, err := os.ReadFile(tc.Fixture)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			m := snsMessage{}
+			if err := json.Unmarshal(b, &m); err != nil {
+				t.Fatal(err)
+			}
+
+			// point the urls to the test server and sign the message
+			m.SigningCertURL = sns.URL + "/SimpleNotificationService.pem"
+			if m.SubscribeURL != "" {
+				m.SubscribeURL = sns.URL + "/?Action=ConfirmSubscription&Token=valid"
+			}
+
+			m.SignatureVersion = "1"
+			if tc.Modify != nil {
+				tc.Modify(&m)
+			}
+
+			m.Signature = sign(t, key, m)
+			if tc.Tamper != nil {
+				tc.Tamper(&m)
+			}
+
+			body, err := json.Marshal(m)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var scans []autoscan.Scan
+			trigger, err := New(Config{
+				Name:     "s3",
+				Priority: 5,
+				Topics:   tc.Topics,
+				Rewrite: []autoscan.Rewrite{{
+					From: "^/media/",
+					To:   "/mnt/unionfs/Media/",
+				}},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			server := httptest.NewServer(trigger(func(s ...autoscan.Scan) error {
+				scans = s
+				return nil
+			}))
+			defer server.Close()
+
+			res, err := http.Post(server.URL, "application/json", bytes.NewReader(body))
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+
+			defer res.Body.Close()
+			if res.StatusCode != tc.StatusCode {
+				t.Errorf("Status codes do not match: %d vs %d", res.StatusCode, tc.StatusCode)
+			}
+
+			if !reflect.DeepEqual(scans, tc.Scans) {
+				t.Logf("want: %v", tc.Scans)
+				t.Logf("got:  %v", scans)
+				t.Errorf("Scans do not equal")
+			}
+
+			if confirmed != tc.Confirmed {
+				t.Errorf("Expected confirmed to be %t", tc.Confirmed)
+			}
+		})
+	}
+}
+
+// sign signs a message like SNS does with the given key.
+func sign(t *testing.T, key *rsa.PrivateKey, m snsMessage) string {
+	hash := crypto.SHA1
+	if m.SignatureVersion == "2" {
+		hash = crypto.SHA256
+	}
+
+	h := hash.New()
+	h.Write([]byte(m.stringToSign()))
+
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, hash, h.Sum(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return base64.StdEncoding.EncodeToString(signature)
+}
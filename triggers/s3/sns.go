@@ -0,0 +1,183 @@
+package s3
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// snsHost matches the hosts of the signing certificates and subscription URLs of AWS SNS.
+var snsHost = regexp.MustCompile(`^sns\.[a-z0-9-]+\.amazonaws\.com(\.cn)?$`)
+
+var snsClient = &http.Client{Timeout: 30 * time.Second}
+
+// snsMessage holds the fields of the AWS SNS envelope.
+type snsMessage struct {
+	Type             string `json:"Type"`
+	MessageID        string `json:"MessageId"`
+	TopicArn         string `json:"TopicArn"`
+	Subject          string `json:"Subject"`
+	Message          string `json:"Message"`
+	Timestamp        string `json:"Timestamp"`
+	Token            string `json:"Token"`
+	SubscribeURL     string `json:"SubscribeURL"`
+	SignatureVersion string `json:"SignatureVersion"`
+	Signature        string `json:"Signature"`
+	SigningCertURL   string `json:"SigningCertURL"`
+}
+
+// stringToSign returns the fields of the message covered by the signature, in the order defined by SNS.
+func (m snsMessage) stringToSign() string {
+	fields := [][2]string{
+		{"Message", m.Message},
+		{"MessageId", m.MessageID},
+	}
+
+	if m.Type == "Notification" {
+		if m.Subject != "" {
+			fields = append(fields, [2]string{"Subject", m.Subject})
+		}
+	} else {
+		fields = append(fields, [2]string{"SubscribeURL", m.SubscribeURL})
+	}
+
+	fields = append(fields, [2]string{"Timestamp", m.Timestamp})
+	if m.Type != "Notification" {
+		fields = append(fields, [2]string{"Token", m.Token})
+	}
+
+	fields = append(fields, [2]string{"TopicArn", m.TopicArn}, [2]string{"Type", m.Type})
+
+	var b strings.Builder
+	for _, f := range fields {
+		b.WriteString(f[0] + "\n" + f[1] + "\n")
+	}
+
+	return b.String()
+}
+
+// snsURL parses a URL given by a message, which must point to SNS.
+func snsURL(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Scheme != "https" || !snsHost.MatchString(u.Hostname()) {
+		return nil, fmt.Errorf("not an SNS url: %v", rawURL)
+	}
+
+	return u, nil
+}
+
+// snsVerifier verifies the signatures of SNS messages, caching the signing certificates.
+type snsVerifier struct {
+	mtx   sync.Mutex
+	certs map[string]*x509.Certificate
+}
+
+func newSNSVerifier() *snsVerifier {
+	return &snsVerifier{
+		certs: make(map[string]*x509.Certificate),
+	}
+}
+
+// verify checks whether the message is signed by SNS.
+func (v *snsVerifier) verify(m snsMessage) error {
+	var algorithm x509.SignatureAlgorithm
+	switch m.SignatureVersion {
+	case "1":
+		algorithm = x509.SHA1WithRSA
+	case "2":
+		algorithm = x509.SHA256WithRSA
+	default:
+		return fmt.Errorf("unsupported signature version: %q", m.SignatureVersion)
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(m.Signature)
+	if err != nil {
+		return fmt.Errorf("decoding signature: %w", err)
+	}
+
+	cert, err := v.certificate(m.SigningCertURL)
+	if err != nil {
+		return fmt.Errorf("signing certificate: %w", err)
+	}
+
+	if err := cert.CheckSignature(algorithm, []byte(m.stringToSign()), signature); err != nil {
+		return fmt.Errorf("checking signature: %w", err)
+	}
+
+	return nil
+}
+
+func (v *snsVerifier) certificate(rawURL string) (*x509.Certificate, error) {
+	u, err := snsURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+
+	if cert, ok := v.certs[u.String()]; ok {
+		return cert, nil
+	}
+
+	res, err := snsClient.Get(u.String())
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("retrieving certificate: %s", res.Status)
+	}
+
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.New("certificate is not PEM encoded")
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	v.certs[u.String()] = cert
+	return cert, nil
+}
+
+// confirmSubscription confirms the subscription of a verified SubscriptionConfirmation message.
+func confirmSubscription(m snsMessage) error {
+	u, err := snsURL(m.SubscribeURL)
+	if err != nil {
+		return err
+	}
+
+	res, err := snsClient.Get(u.String())
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("confirming subscription: %s", res.Status)
+	}
+
+	return nil
+}
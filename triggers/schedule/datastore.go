@@ -0,0 +1,59 @@
+package schedule
+
+import (
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+
+	"github.com/cloudbox/autoscan/migrate"
+)
+
+type datastore struct {
+	*sql.DB
+}
+
+var (
+	//go:embed migrations
+	migrations embed.FS
+)
+
+func newDatastore(db *sql.DB, mg *migrate.Migrator) (*datastore, error) {
+	// migrations
+	if err := mg.Migrate(&migrations, "schedule"); err != nil {
+		return nil, fmt.Errorf("migrate: %w", err)
+	}
+
+	return &datastore{db}, nil
+}
+
+const sqlSelectRun = `SELECT run FROM schedule_rotation WHERE name = ?`
+
+// Run returns the number of completed runs of a rotation.
+func (store *datastore) Run(name string) (int64, error) {
+	row := store.QueryRow(sqlSelectRun, name)
+
+	var run int64
+	err := row.Scan(&run)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return 0, nil
+	case err != nil:
+		return 0, err
+	}
+
+	return run, nil
+}
+
+const sqlUpsertRun = `
+INSERT INTO schedule_rotation (name, run)
+VALUES (?, ?)
+ON CONFLICT (name) DO UPDATE SET
+	run = excluded.run
+`
+
+// SetRun stores the number of completed runs of a rotation.
+func (store *datastore) SetRun(name string, run int64) error {
+	_, err := store.Exec(sqlUpsertRun, name, run)
+	return err
+}
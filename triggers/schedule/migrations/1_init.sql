@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS schedule_rotation (
+    "name" TEXT NOT NULL,
+    "run" INTEGER NOT NULL,
+    PRIMARY KEY(name)
+)
@@ -0,0 +1,267 @@
+package schedule
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/rs/zerolog"
+
+	"github.com/cloudbox/autoscan"
+	"github.com/cloudbox/autoscan/migrate"
+)
+
+type Config struct {
+	Cron           string             `yaml:"cron"`
+	Priority       int                `yaml:"priority"`
+	Paths          []string           `yaml:"paths"`
+	Libraries      bool               `yaml:"libraries"`
+	Rotate         int                `yaml:"rotate"`
+	Verbosity      string             `yaml:"verbosity"`
+	Rewrite        []autoscan.Rewrite `yaml:"rewrite"`
+	LibraryRewrite []autoscan.Rewrite `yaml:"library-rewrite"`
+	Include        []string           `yaml:"include"`
+	Exclude        []string           `yaml:"exclude"`
+}
+
+type daemon struct {
+	callback autoscan.ProcessorFunc
+	store    *datastore
+	name     string
+	paths    []string
+	targets  []autoscan.LibraryTarget
+	priority int
+	rotate   int
+	rewrite  autoscan.Rewriter
+	allowed  autoscan.Filterer
+	log      zerolog.Logger
+
+	// translates the library folders of the targets to local paths
+	libraryRewrite autoscan.Rewriter
+}
+
+// New creates an autoscan-compatible Trigger which periodically scans the configured paths
+// and/or the library folders of the targets.
+// When rotation is enabled, only a part of the sub-folders of each path is scanned per run.
+// The runs are counted in the datastore, so restarts do not reset the rotation.
+func New(c Config, targets []autoscan.Target, db *sql.DB, mg *migrate.Migrator) (autoscan.Trigger, error) {
+	l := autoscan.GetLogger(c.Verbosity).With().
+		Str("trigger", "schedule").
+		Str("cron", c.Cron).
+		Logger()
+
+	schedule, err := cron.ParseStandard(c.Cron)
+	if err != nil {
+		return nil, fmt.Errorf("parsing cron schedule: %v: %w", err, autoscan.ErrFatal)
+	}
+
+	if len(c.Paths) == 0 && !c.Libraries {
+		return nil, fmt.Errorf("no paths or libraries to scan: %w", autoscan.ErrFatal)
+	}
+
+	store, err := newDatastore(db, mg)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", err, autoscan.ErrFatal)
+	}
+
+	rewriter, err := autoscan.NewRewriter(c.Rewrite)
+	if err != nil {
+		return nil, err
+	}
+
+	libraryRewriter, err := autoscan.NewRewriter(c.LibraryRewrite)
+	if err != nil {
+		return nil, err
+	}
+
+	filterer, err := autoscan.NewFilterer(c.Include, c.Exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	// the rotation is identified by the schedule and the paths of the trigger
+	name := fmt.Sprintf("%s|%t|%s", c.Cron, c.Libraries, strings.Join(c.Paths, "|"))
+
+	libraryTargets := make([]autoscan.LibraryTarget, 0)
+	if c.Libraries {
+		for _, t := range targets {
+			if lt, ok := t.(autoscan.LibraryTarget); ok {
+				libraryTargets = append(libraryTargets, lt)
+			}
+		}
+	}
+
+	trigger := func(callback autoscan.ProcessorFunc) {
+		d := &daemon{
+			callback: callback,
+			store:    store,
+			name:     name,
+			paths:    c.Paths,
+			targets:  libraryTargets,
+			priority: c.Priority,
+			rotate:   c.Rotate,
+			rewrite:  rewriter,
+			allowed:  filterer,
+			log:      l,
+
+			libraryRewrite: libraryRewriter,
+		}
+
+		cr := cron.New()
+		cr.Schedule(schedule, cron.FuncJob(d.run))
+		cr.Start()
+
+		l.Info().
+			Time("next", schedule.Next(time.Now())).
+			Msg("Scheduled scans")
+	}
+
+	return trigger, nil
+}
+
+func (d *daemon) run() {
+	run, err := d.store.Run(d.name)
+	if err != nil {
+		d.log.Error().
+			Err(err).
+			Msg("Failed retrieving rotation")
+		return
+	}
+
+	slot := d.slot(run)
+
+	folders, err := d.folders(slot)
+	if err != nil {
+		d.log.Error().
+			Err(err).
+			Msg("Failed determining folders")
+		return
+	}
+
+	scans := make([]autoscan.Scan, 0, len(folders))
+	for _, folder := range folders {
+		scans = append(scans, autoscan.Scan{
+			Folder:   folder,
+			Priority: d.priority,
+			Time:     now(),
+		})
+	}
+
+	if len(scans) > 0 {
+		if err := d.callback(scans...); err != nil {
+			d.log.Error().
+				Err(err).
+				Msg("Processor could not process scans")
+			return
+		}
+
+		d.log.Info().
+			Int("scans", len(scans)).
+			Int("slot", slot).
+			Msg("Scans moved to processor")
+	}
+
+	// move to the next slot once the scans are stored
+	if err := d.store.SetRun(d.name, run+1); err != nil {
+		d.log.Error().
+			Err(err).
+			Msg("Failed storing rotation")
+	}
+}
+
+// roots returns the configured paths and the library folders of the targets, rewritten to local paths.
+// The configured paths use the rewrite rules of the trigger, while the library folders are paths
+// on the file system of the targets and use the library rewrite rules instead.
+func (d *daemon) roots() []string {
+	paths := make([]string, 0, len(d.paths))
+	for _, p := range d.paths {
+		paths = append(paths, d.rewrite(p))
+	}
+
+	for _, t := range d.targets {
+		for _, p := range t.Libraries() {
+			paths = append(paths, d.libraryRewrite(p))
+		}
+	}
+
+	unique := make(map[string]bool)
+	roots := make([]string, 0, len(paths))
+
+	for _, p := range paths {
+		folderPath := path.Clean(p)
+		if unique[folderPath] || !d.allowed(folderPath) {
+			continue
+		}
+
+		unique[folderPath] = true
+		roots = append(roots, folderPath)
+	}
+
+	return roots
+}
+
+// folders returns the folders to scan in the given rotation slot.
+// Without rotation, the roots themselves are scanned.
+func (d *daemon) folders(slot int) ([]string, error) {
+	roots := d.roots()
+	if d.rotate <= 1 {
+		return roots, nil
+	}
+
+	folders := make([]string, 0)
+	for _, root := range roots {
+		entries, err := os.ReadDir(root)
+		if errors.Is(err, os.ErrNotExist) {
+			d.log.Warn().
+				Str("path", root).
+				Msg("Path does not exist")
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("read dir: %w", err)
+		}
+
+		for _, e := range entries {
+			if !e.IsDir() {
+				continue
+			}
+
+			folderPath := filepath.Join(root, e.Name())
+			if rotationSlot(e.Name(), d.rotate) == slot && d.allowed(folderPath) {
+				folders = append(folders, folderPath)
+			}
+		}
+	}
+
+	sort.Strings(folders)
+	return folders, nil
+}
+
+// slot returns the rotation slot of a run,
+// consecutive runs move to the next slot regardless of the time between them.
+func (d *daemon) slot(run int64) int {
+	if d.rotate <= 1 {
+		return 0
+	}
+
+	return int(run % int64(d.rotate))
+}
+
+// rotationSlot assigns a folder to a slot by its name,
+// so new folders do not shift the slots of existing folders.
+func rotationSlot(name string, rotate int) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(name))
+	return int(h.Sum32() % uint32(rotate))
+}
+
+var now = time.Now
@@ -0,0 +1,237 @@
+package schedule
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/rs/zerolog"
+
+	"github.com/cloudbox/autoscan"
+	"github.com/cloudbox/autoscan/migrate"
+
+	// sqlite3 driver
+	_ "modernc.org/sqlite"
+)
+
+func getDatastore(t *testing.T) *datastore {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every connection opens a separate in-memory database
+	db.SetMaxOpenConns(1)
+
+	mg, err := migrate.New(db, "migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := newDatastore(db, mg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return store
+}
+
+func TestRotation(t *testing.T) {
+	root := t.TempDir()
+	names := []string{"Interstellar", "Parasite", "Tenet", "Dune", "Arrival", "Heat", "Alien"}
+	for _, name := range names {
+		if err := os.Mkdir(filepath.Join(root, name), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rewriter, _ := autoscan.NewRewriter(nil)
+	filterer, _ := autoscan.NewFilterer(nil, nil)
+
+	d := &daemon{
+		paths:   []string{root},
+		rotate:  7,
+		rewrite: rewriter,
+		allowed: filterer,
+		log:     zerolog.Nop(),
+	}
+
+	// every folder is scanned exactly once within a rotation
+	scanned := make([]string, 0)
+	for slot := 0; slot < d.rotate; slot++ {
+		folders, err := d.folders(slot)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		scanned = append(scanned, folders...)
+	}
+
+	expected := make([]string, 0)
+	for _, name := range names {
+		expected = append(expected, filepath.Join(root, name))
+	}
+
+	sort.Strings(scanned)
+	sort.Strings(expected)
+	if len(scanned) != len(expected) {
+		t.Fatalf("Expected %d folders, got %d: %v", len(expected), len(scanned), scanned)
+	}
+
+	for i := range expected {
+		if scanned[i] != expected[i] {
+			t.Errorf("Folders do not equal: %v", scanned)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	root := t.TempDir()
+	names := []string{"Interstellar", "Parasite", "Tenet", "Dune", "Arrival", "Heat", "Alien"}
+	for _, name := range names {
+		if err := os.Mkdir(filepath.Join(root, name), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rewriter, _ := autoscan.NewRewriter(nil)
+	filterer, _ := autoscan.NewFilterer(nil, nil)
+
+	// runs on mondays, wednesdays and fridays, the time between runs differs
+	schedule, err := cron.ParseStandard("0 3 * * 1,3,5")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := getDatastore(t)
+	defer func() {
+		now = time.Now
+	}()
+
+	var scanned []string
+	newDaemon := func() *daemon {
+		return &daemon{
+			callback: func(scans ...autoscan.Scan) error {
+				for _, s := range scans {
+					scanned = append(scanned, s.Folder)
+				}
+				return nil
+			},
+			store:   store,
+			name:    "schedule",
+			paths:   []string{root},
+			rotate:  4,
+			rewrite: rewriter,
+			allowed: filterer,
+			log:     zerolog.Nop(),
+		}
+	}
+
+	d := newDaemon()
+	run := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 3*d.rotate; i++ {
+		// the daemon is restarted halfway, the rotation continues
+		if i == 5 {
+			d = newDaemon()
+		}
+
+		run = schedule.Next(run)
+		now = func() time.Time {
+			return run
+		}
+
+		scanned = nil
+		d.run()
+
+		expected, err := d.folders(i % d.rotate)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !reflect.DeepEqual(scanned, expected) {
+			t.Logf("want: %v", expected)
+			t.Logf("got:  %v", scanned)
+			t.Errorf("Folders of run %d on %v do not equal", i, run.Weekday())
+		}
+	}
+}
+
+type libraryTarget struct {
+	libraries []string
+}
+
+func (t libraryTarget) Scan(autoscan.Scan) error { return nil }
+func (t libraryTarget) Available() error         { return nil }
+func (t libraryTarget) Libraries() []string      { return t.libraries }
+
+func TestLibraries(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"Movies/Interstellar", "Movies/Parasite", "TV/Westworld"} {
+		if err := os.MkdirAll(filepath.Join(root, name), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the paths of the trigger and the libraries of the target use different prefixes
+	rewriter, _ := autoscan.NewRewriter([]autoscan.Rewrite{{
+		From: "^/downloads/",
+		To:   root + "/",
+	}})
+
+	libraryRewriter, _ := autoscan.NewRewriter([]autoscan.Rewrite{{
+		From: "^/data/",
+		To:   root + "/",
+	}})
+
+	filterer, _ := autoscan.NewFilterer(nil, nil)
+
+	d := &daemon{
+		paths: []string{"/downloads/TV"},
+		targets: []autoscan.LibraryTarget{
+			libraryTarget{libraries: []string{"/data/Movies", "/data/TV/"}},
+		},
+		rewrite:        rewriter,
+		libraryRewrite: libraryRewriter,
+		allowed:        filterer,
+		log:            zerolog.Nop(),
+	}
+
+	expected := []string{filepath.Join(root, "TV"), filepath.Join(root, "Movies")}
+	if roots := d.roots(); !reflect.DeepEqual(roots, expected) {
+		t.Logf("want: %v", expected)
+		t.Logf("got:  %v", roots)
+		t.Errorf("Roots do not equal")
+	}
+
+	// rotation reads the sub-folders of the library from the local path
+	d.rotate = 4
+
+	scanned := make([]string, 0)
+	for slot := 0; slot < d.rotate; slot++ {
+		folders, err := d.folders(slot)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		scanned = append(scanned, folders...)
+	}
+
+	expected = []string{
+		filepath.Join(root, "Movies/Interstellar"),
+		filepath.Join(root, "Movies/Parasite"),
+		filepath.Join(root, "TV/Westworld"),
+	}
+
+	sort.Strings(scanned)
+	if !reflect.DeepEqual(scanned, expected) {
+		t.Logf("want: %v", expected)
+		t.Logf("got:  %v", scanned)
+		t.Errorf("Folders do not equal")
+	}
+}
@@ -2,6 +2,7 @@ package sonarr
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"path"
 	"strings"
@@ -43,22 +44,140 @@ type handler struct {
 	callback autoscan.ProcessorFunc
 }
 
+type episodeFile struct {
+	// Path is only included by Sonarr v3 and later.
+	Path         string `json:"path"`
+	RelativePath string `json:"relativePath"`
+
+	// PreviousPath is only included in renamed episode files.
+	// Use PreviousPath as the Series.Path might have changed.
+	PreviousPath string `json:"previousPath"`
+}
+
+// folder returns the folder of the episode file,
+// falling back to the relative path within the series folder.
+func (f episodeFile) folder(seriesPath string) string {
+	switch {
+	case f.Path != "":
+		return path.Dir(f.Path)
+	case f.RelativePath != "" && seriesPath != "":
+		return path.Dir(path.Join(seriesPath, f.RelativePath))
+	default:
+		return ""
+	}
+}
+
 type sonarrEvent struct {
 	Type string `json:"eventType"`
 
-	File struct {
-		RelativePath string
-	} `json:"episodeFile"`
+	File  episodeFile   `json:"episodeFile"`
+	Files []episodeFile `json:"episodeFiles"`
 
 	Series struct {
 		Path string
 	} `json:"series"`
 
-	RenamedFiles []struct {
-		// use PreviousPath as the Series.Path might have changed.
-		PreviousPath string
-		RelativePath string
-	} `json:"renamedEpisodeFiles"`
+	RenamedFiles []episodeFile `json:"renamedEpisodeFiles"`
+
+	// DeletedFiles holds the replaced files of an upgrade,
+	// while it is a boolean on the SeriesDelete event.
+	DeletedFiles json.RawMessage `json:"deletedFiles"`
+
+	IsUpgrade       bool   `json:"isUpgrade"`
+	DeleteReason    string `json:"deleteReason"`
+	DestinationPath string `json:"destinationPath"`
+}
+
+// deletedFiles returns the files which were replaced by an upgrade.
+func (e sonarrEvent) deletedFiles() ([]episodeFile, error) {
+	files := make([]episodeFile, 0)
+	if len(e.DeletedFiles) == 0 || e.DeletedFiles[0] != '[' {
+		return files, nil
+	}
+
+	if err := json.Unmarshal(e.DeletedFiles, &files); err != nil {
+		return nil, fmt.Errorf("decoding deleted files: %w", err)
+	}
+
+	return files, nil
+}
+
+// paths returns the folders affected by the event.
+// Nil is returned for events which do not affect any folders.
+func (e sonarrEvent) paths() ([]string, error) {
+	seriesPath := e.Series.Path
+	paths := make([]string, 0)
+
+	add := func(folderPath string) {
+		if folderPath != "" {
+			paths = append(paths, folderPath)
+		}
+	}
+
+	switch strings.ToLower(e.Type) {
+	// a Download event is either an upgrade or a new file.
+	// the EpisodeFileDelete event shares the same request format as Download.
+	// ImportComplete is sent once all files of a release were imported.
+	case "download", "episodefiledelete", "importcomplete":
+		add(e.File.folder(seriesPath))
+		for _, f := range e.Files {
+			add(f.folder(seriesPath))
+		}
+
+		// the replaced files of an upgrade might have been in another folder
+		deleted, err := e.deletedFiles()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, f := range deleted {
+			add(f.folder(seriesPath))
+		}
+
+		// manual imports only include the destination
+		if len(paths) == 0 && e.DestinationPath != "" {
+			if path.Ext(e.DestinationPath) != "" {
+				add(path.Dir(e.DestinationPath))
+			} else {
+				add(path.Clean(e.DestinationPath))
+			}
+		}
+
+	// an entire show has been added or deleted, scan the folder of the show
+	case "seriesadd", "seriesdelete":
+		add(seriesPath)
+
+	case "rename":
+		for _, f := range e.RenamedFiles {
+			if f.PreviousPath != "" {
+				add(path.Dir(f.PreviousPath))
+			}
+
+			add(f.folder(seriesPath))
+		}
+
+		// without the renamed files, scan the entire show instead
+		if len(paths) == 0 {
+			add(seriesPath)
+		}
+
+	default:
+		return nil, nil
+	}
+
+	// remove duplicates while keeping the order
+	encountered := make(map[string]bool)
+	unique := make([]string, 0, len(paths))
+	for _, p := range paths {
+		if encountered[p] {
+			continue
+		}
+
+		encountered[p] = true
+		unique = append(unique, p)
+	}
+
+	return unique, nil
 }
 
 func (h handler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
@@ -81,60 +200,24 @@ func (h handler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var paths []string
-
-	// a Download event is either an upgrade or a new file.
-	// the EpisodeFileDelete event shares the same request format as Download.
-	if strings.EqualFold(event.Type, "Download") || strings.EqualFold(event.Type, "EpisodeFileDelete") {
-		if event.File.RelativePath == "" || event.Series.Path == "" {
-			rlog.Error().Msg("Required fields are missing")
-			rw.WriteHeader(http.StatusBadRequest)
-			return
-		}
-
-		// Use path.Dir to get the directory in which the file is located
-		folderPath := path.Dir(path.Join(event.Series.Path, event.File.RelativePath))
-		paths = append(paths, folderPath)
+	paths, err := event.paths()
+	if err != nil {
+		rlog.Error().Err(err).Msg("Failed decoding request")
+		rw.WriteHeader(http.StatusBadRequest)
+		return
 	}
 
-	// An entire show has been deleted
-	if strings.EqualFold(event.Type, "SeriesDelete") {
-		if event.Series.Path == "" {
-			rlog.Error().Msg("Required fields are missing")
-			rw.WriteHeader(http.StatusBadRequest)
-			return
-		}
-
-		// Scan the folder of the show
-		paths = append(paths, event.Series.Path)
+	if paths == nil {
+		// Grab, Health, ApplicationUpdate and other events without file system changes
+		rlog.Debug().Str("event", event.Type).Msg("Ignoring event")
+		rw.WriteHeader(http.StatusOK)
+		return
 	}
 
-	if strings.EqualFold(event.Type, "Rename") {
-		if event.Series.Path == "" {
-			rlog.Error().Msg("Required fields are missing")
-			rw.WriteHeader(http.StatusBadRequest)
-			return
-		}
-
-		// Keep track of which paths we have already added to paths.
-		encountered := make(map[string]bool)
-
-		for _, renamedFile := range event.RenamedFiles {
-			previousPath := path.Dir(renamedFile.PreviousPath)
-			currentPath := path.Dir(path.Join(event.Series.Path, renamedFile.RelativePath))
-
-			// if previousPath not in paths, then add it.
-			if _, ok := encountered[previousPath]; !ok {
-				encountered[previousPath] = true
-				paths = append(paths, previousPath)
-			}
-
-			// if currentPath not in paths, then add it.
-			if _, ok := encountered[currentPath]; !ok {
-				encountered[currentPath] = true
-				paths = append(paths, currentPath)
-			}
-		}
+	if len(paths) == 0 {
+		rlog.Error().Msg("Required fields are missing")
+		rw.WriteHeader(http.StatusBadRequest)
+		return
 	}
 
 	var scans []autoscan.Scan
@@ -162,6 +245,7 @@ func (h handler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 		rlog.Info().
 			Str("path", scan.Folder).
 			Str("event", event.Type).
+			Bool("upgrade", event.IsUpgrade || strings.EqualFold(event.DeleteReason, "upgrade")).
 			Msg("Scan moved to processor")
 	}
 
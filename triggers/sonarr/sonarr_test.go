@@ -127,6 +127,197 @@ func TestHandler(t *testing.T) {
 				},
 			},
 		},
+		{
+			"Scans the folders of new and replaced files on upgrade",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/upgrade.json",
+			},
+			Expected{
+				StatusCode: 200,
+				Scans: []autoscan.Scan{
+					{
+						Folder:   "/mnt/unionfs/Media/TV/Westworld/Season 1",
+						Priority: 5,
+						Time:     currentTime,
+					},
+					{
+						Folder:   "/mnt/unionfs/Media/TV/Westworld/Season 01",
+						Priority: 5,
+						Time:     currentTime,
+					},
+				},
+			},
+		},
+		{
+			"Scans every folder of a multi-file ImportComplete event",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/import_complete.json",
+			},
+			Expected{
+				StatusCode: 200,
+				Scans: []autoscan.Scan{
+					{
+						Folder:   "/mnt/unionfs/Media/TV/Westworld/Season 2",
+						Priority: 5,
+						Time:     currentTime,
+					},
+					{
+						Folder:   "/mnt/unionfs/Media/TV/Westworld/Season 3",
+						Priority: 5,
+						Time:     currentTime,
+					},
+				},
+			},
+		},
+		{
+			"Falls back to the destination path on manual imports",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/manual_import.json",
+			},
+			Expected{
+				StatusCode: 200,
+				Scans: []autoscan.Scan{
+					{
+						Folder:   "/mnt/unionfs/Media/TV/Westworld/Season 4",
+						Priority: 5,
+						Time:     currentTime,
+					},
+				},
+			},
+		},
+		{
+			"Scan on EpisodeFileDelete for upgrade",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/episode_delete_upgrade.json",
+			},
+			Expected{
+				StatusCode: 200,
+				Scans: []autoscan.Scan{
+					{
+						Folder:   "/mnt/unionfs/Media/TV/Westworld/Season 01",
+						Priority: 5,
+						Time:     currentTime,
+					},
+				},
+			},
+		},
+		{
+			"Scans show folder on SeriesAdd event",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/series_add.json",
+			},
+			Expected{
+				StatusCode: 200,
+				Scans: []autoscan.Scan{
+					{
+						Folder:   "/mnt/unionfs/Media/TV/Severance",
+						Priority: 5,
+						Time:     currentTime,
+					},
+				},
+			},
+		},
+		{
+			"Scans show folder on SeriesDelete event with deleted files",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/series_delete_files.json",
+			},
+			Expected{
+				StatusCode: 200,
+				Scans: []autoscan.Scan{
+					{
+						Folder:   "/mnt/unionfs/Media/TV/Westworld",
+						Priority: 5,
+						Time:     currentTime,
+					},
+				},
+			},
+		},
+		{
+			"Prefers the full paths on the Rename event",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/rename_paths.json",
+			},
+			Expected{
+				StatusCode: 200,
+				Scans: []autoscan.Scan{
+					{
+						Folder:   "/mnt/unionfs/Media/TV/Westworld/Season 1",
+						Priority: 5,
+						Time:     currentTime,
+					},
+					{
+						Folder:   "/mnt/unionfs/Media/TV/Westworld/Season 01",
+						Priority: 5,
+						Time:     currentTime,
+					},
+				},
+			},
+		},
+		{
+			"Scans the show folder on a Rename event without renamed files",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/rename_series.json",
+			},
+			Expected{
+				StatusCode: 200,
+				Scans: []autoscan.Scan{
+					{
+						Folder:   "/mnt/unionfs/Media/TV/Westworld",
+						Priority: 5,
+						Time:     currentTime,
+					},
+				},
+			},
+		},
+		{
+			"Returns bad request on malformed deleted files",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/malformed_deleted_files.json",
+			},
+			Expected{
+				StatusCode: 400,
+			},
+		},
+		{
+			"Returns bad request when required fields are missing",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/missing_fields.json",
+			},
+			Expected{
+				StatusCode: 400,
+			},
+		},
+		{
+			"Returns 200 on Grab event without emitting a scan",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/grab.json",
+			},
+			Expected{
+				StatusCode: 200,
+			},
+		},
+		{
+			"Returns 200 on Health event without emitting a scan",
+			Given{
+				Config:  standardConfig,
+				Fixture: "testdata/health.json",
+			},
+			Expected{
+				StatusCode: 200,
+			},
+		},
 		{
 			"Returns bad request on invalid JSON",
 			Given{
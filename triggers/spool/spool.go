@@ -0,0 +1,242 @@
+package spool
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/cloudbox/autoscan"
+	"github.com/cloudbox/autoscan/triggers/internal/message"
+)
+
+type Config struct {
+	Path      string             `yaml:"path"`
+	Interval  time.Duration      `yaml:"interval"`
+	Archive   bool               `yaml:"archive"`
+	Priority  int                `yaml:"priority"`
+	Dirname   bool               `yaml:"dirname"`
+	Verbosity string             `yaml:"verbosity"`
+	Rewrite   []autoscan.Rewrite `yaml:"rewrite"`
+	Include   []string           `yaml:"include"`
+	Exclude   []string           `yaml:"exclude"`
+}
+
+const (
+	defaultInterval = 10 * time.Second
+
+	// files which were modified more recently might still be written to
+	minimumAge = 2 * time.Second
+
+	archiveFolder = "processed"
+	errorFolder   = "error"
+)
+
+type daemon struct {
+	message.Handler
+	callback autoscan.ProcessorFunc
+	path     string
+	archive  bool
+	log      zerolog.Logger
+}
+
+// New creates an autoscan-compatible Trigger which periodically reads the *.json and *.txt files
+// dropped into a spool directory, for tools which cannot call webhooks.
+// Processed files are deleted or archived, malformed files are moved to the error folder.
+func New(c Config) (autoscan.Trigger, error) {
+	l := autoscan.GetLogger(c.Verbosity).With().
+		Str("trigger", "spool").
+		Str("path", c.Path).
+		Logger()
+
+	if c.Path == "" {
+		return nil, fmt.Errorf("spool path is required: %w", autoscan.ErrFatal)
+	}
+
+	rewriter, err := autoscan.NewRewriter(c.Rewrite)
+	if err != nil {
+		return nil, err
+	}
+
+	filterer, err := autoscan.NewFilterer(c.Include, c.Exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	interval := c.Interval
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+
+	trigger := func(callback autoscan.ProcessorFunc) {
+		d := daemon{
+			Handler: message.Handler{
+				Priority: c.Priority,
+				Dirname:  c.Dirname,
+				Rewrite:  rewriter,
+				Allowed:  filterer,
+			},
+			callback: callback,
+			path:     filepath.Clean(c.Path),
+			archive:  c.Archive,
+			log:      l,
+		}
+
+		t := time.NewTicker(interval)
+		defer t.Stop()
+
+		for {
+			if err := d.process(); err != nil {
+				l.Error().
+					Err(err).
+					Msg("Failed processing spool directory")
+			}
+
+			<-t.C
+		}
+	}
+
+	return trigger, nil
+}
+
+// process handles all files in the spool directory which are ready.
+// Files which could not be processed are retried on the next run, without holding up the other files.
+func (d *daemon) process() error {
+	entries, err := os.ReadDir(d.path)
+	if err != nil {
+		return fmt.Errorf("read dir: %w", err)
+	}
+
+	for _, e := range entries {
+		name := e.Name()
+		ext := strings.ToLower(filepath.Ext(name))
+		if e.IsDir() || strings.HasPrefix(name, ".") || (ext != ".json" && ext != ".txt") {
+			continue
+		}
+
+		info, err := e.Info()
+		if err != nil || time.Since(info.ModTime()) < minimumAge {
+			// removed or still being written to
+			continue
+		}
+
+		if err := d.processFile(name); err != nil {
+			d.log.Error().
+				Err(err).
+				Str("file", name).
+				Msg("Failed processing file")
+		}
+	}
+
+	return nil
+}
+
+func (d *daemon) processFile(name string) error {
+	l := d.log.With().Str("file", name).Logger()
+	filePath := filepath.Join(d.path, name)
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("read file: %v: %w", name, err)
+	}
+
+	m, err := parse(name, data)
+
+	var scans []autoscan.Scan
+	if err == nil {
+		scans, err = d.MessageScans(m)
+	}
+
+	if err != nil {
+		l.Error().
+			Err(err).
+			Msg("Malformed file, moving to error folder")
+		return d.move(name, errorFolder)
+	}
+
+	if len(scans) > 0 {
+		// keep the file around when the scans could not be stored
+		if err := d.callback(scans...); err != nil {
+			return fmt.Errorf("moving scans to processor: %v: %w", err, autoscan.ErrFatal)
+		}
+	}
+
+	for _, scan := range scans {
+		l.Info().
+			Str("scan", scan.Folder).
+			Msg("Scan moved to processor")
+	}
+
+	if d.archive {
+		return d.move(name, archiveFolder)
+	}
+
+	if err := os.Remove(filePath); err != nil {
+		return fmt.Errorf("remove file: %v: %w", name, err)
+	}
+
+	return nil
+}
+
+// move moves a file into a sub-folder of the spool directory, prefixed with the current time.
+func (d *daemon) move(name string, folder string) error {
+	dir := filepath.Join(d.path, folder)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return fmt.Errorf("create folder: %v: %w", folder, err)
+	}
+
+	target := filepath.Join(dir, fmt.Sprintf("%s-%s", now().Format("20060102-150405"), name))
+	if err := os.Rename(filepath.Join(d.path, name), target); err != nil {
+		return fmt.Errorf("move file: %v: %w", name, err)
+	}
+
+	return nil
+}
+
+// parse reads the paths of a spool file into a message.
+// JSON files either contain an array of paths, or a message with the path or paths and an optional priority.
+// Text files contain a path per line, empty lines and lines starting with # are ignored.
+func parse(name string, data []byte) (*message.Message, error) {
+	m := new(message.Message)
+
+	if strings.EqualFold(filepath.Ext(name), ".json") {
+		data = bytes.TrimSpace(data)
+		if bytes.HasPrefix(data, []byte("[")) {
+			if err := json.Unmarshal(data, &m.Paths); err != nil {
+				return nil, err
+			}
+		} else if err := json.Unmarshal(data, m); err != nil {
+			return nil, err
+		}
+	} else {
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+
+			m.Paths = append(m.Paths, line)
+		}
+
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, p := range m.AllPaths() {
+		if !strings.HasPrefix(p, "/") {
+			return nil, fmt.Errorf("path is not absolute: %v", p)
+		}
+	}
+
+	return m, nil
+}
+
+var now = time.Now
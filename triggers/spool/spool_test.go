@@ -0,0 +1,146 @@
+package spool
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cloudbox/autoscan"
+	"github.com/cloudbox/autoscan/triggers/internal/message"
+)
+
+func TestProcess(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, content string) {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		// older than the minimum age
+		old := time.Now().Add(-time.Minute)
+		if err := os.Chtimes(p, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("a.json", `{"paths": ["/downloads/Movies/Interstellar (2014)", "/downloads/Movies/Interstellar (2014)/"], "priority": 7}`)
+	write("b.txt", "# comment\n/downloads/TV/Westworld/Season 1\n\n/downloads/TV/Westworld/Season 1.nfo\n")
+	write("c.json", `["/downloads/Movies/Tenet (2020)"]`)
+	write("d.json", `This is an invalid JSON file`)
+	write("e.txt", "relative/path\n")
+	write("f.mkv", "not a spool file")
+
+	rewriter, err := autoscan.NewRewriter([]autoscan.Rewrite{{
+		From: "^/downloads/",
+		To:   "/mnt/unionfs/Media/",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filterer, err := autoscan.NewFilterer(nil, []string{`\.nfo$`})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	currentTime := time.Now()
+	now = func() time.Time {
+		return currentTime
+	}
+
+	var received []autoscan.Scan
+	d := daemon{
+		Handler: message.Handler{
+			Priority: 2,
+			Rewrite:  rewriter,
+			Allowed:  filterer,
+		},
+		callback: func(scans ...autoscan.Scan) error {
+			for _, scan := range scans {
+				scan.Time = time.Time{}
+				received = append(received, scan)
+			}
+			return nil
+		},
+		path:    dir,
+		archive: true,
+	}
+
+	if err := d.process(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []autoscan.Scan{
+		{Folder: "/mnt/unionfs/Media/Movies/Interstellar (2014)", Priority: 7},
+		{Folder: "/mnt/unionfs/Media/TV/Westworld/Season 1", Priority: 2},
+		{Folder: "/mnt/unionfs/Media/Movies/Tenet (2020)", Priority: 2},
+	}
+
+	if !reflect.DeepEqual(received, expected) {
+		t.Logf("want: %v", expected)
+		t.Logf("got:  %v", received)
+		t.Errorf("Scans do not equal")
+	}
+
+	list := func(folder string) []string {
+		entries, err := os.ReadDir(filepath.Join(dir, folder))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		names := make([]string, 0)
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+
+		return names
+	}
+
+	prefix := currentTime.Format("20060102-150405") + "-"
+
+	if files := list(archiveFolder); !reflect.DeepEqual(files, []string{prefix + "a.json", prefix + "b.txt", prefix + "c.json"}) {
+		t.Errorf("Archived files do not equal: %v", files)
+	}
+
+	if files := list(errorFolder); !reflect.DeepEqual(files, []string{prefix + "d.json", prefix + "e.txt"}) {
+		t.Errorf("Malformed files do not equal: %v", files)
+	}
+
+	if files := list(""); !reflect.DeepEqual(files, []string{errorFolder, "f.mkv", archiveFolder}) {
+		t.Errorf("Remaining files do not equal: %v", files)
+	}
+
+	// files are kept when the scans cannot be stored, without holding up the other files
+	write("g.txt", "/downloads/Movies/Parasite (2019)\n")
+	write("h.txt", "/downloads/Movies/Dune (2021)\n")
+	d.archive = false
+	received = nil
+	d.callback = func(scans ...autoscan.Scan) error {
+		if scans[0].Folder == "/mnt/unionfs/Media/Movies/Parasite (2019)" {
+			return errors.New("database is locked")
+		}
+
+		received = append(received, scans...)
+		return nil
+	}
+
+	if err := d.process(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "g.txt")); err != nil {
+		t.Errorf("Expected the file to be kept: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "h.txt")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected the file to be removed: %v", err)
+	}
+
+	if len(received) != 1 || received[0].Folder != "/mnt/unionfs/Media/Movies/Dune (2021)" {
+		t.Errorf("Expected the next file to be processed, got: %v", received)
+	}
+}
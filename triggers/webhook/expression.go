@@ -0,0 +1,200 @@
+package webhook
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// An extractor returns the values of an expression within a decoded JSON body.
+type extractor func(data interface{}) ([]string, error)
+
+// newExtractor compiles an expression.
+// Expressions starting with $ are JSONPath expressions, all other expressions are Go templates.
+// Each line of the output of a template is a separate value.
+func newExtractor(expr string) (extractor, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return nil, errors.New("empty expression")
+	}
+
+	if strings.HasPrefix(expr, "$") {
+		segments, err := parseJSONPath(expr)
+		if err != nil {
+			return nil, fmt.Errorf("jsonpath: %v: %w", expr, err)
+		}
+
+		return func(data interface{}) ([]string, error) {
+			return values(evaluate([]interface{}{data}, segments)), nil
+		}, nil
+	}
+
+	tmpl, err := template.New("expression").Funcs(templateFuncs).Parse(expr)
+	if err != nil {
+		return nil, fmt.Errorf("template: %v: %w", expr, err)
+	}
+
+	return func(data interface{}) ([]string, error) {
+		buf := new(bytes.Buffer)
+		if err := tmpl.Execute(buf, data); err != nil {
+			return nil, err
+		}
+
+		result := make([]string, 0)
+		for _, line := range strings.Split(buf.String(), "\n") {
+			if line = strings.TrimSpace(line); line != "" && line != "<no value>" {
+				result = append(result, line)
+			}
+		}
+
+		return result, nil
+	}, nil
+}
+
+var templateFuncs = template.FuncMap{
+	"dir":  path.Dir,
+	"base": path.Base,
+	"join": path.Join,
+}
+
+// A segment is a single step of a JSONPath expression.
+type segment struct {
+	Key      string
+	Index    int
+	IsIndex  bool
+	Wildcard bool
+}
+
+// parseJSONPath parses the subset of JSONPath consisting of
+// child keys (.key and ['key']), array indices ([0] and [-1]) and wildcards (.* and [*]).
+func parseJSONPath(expr string) ([]segment, error) {
+	segments := make([]segment, 0)
+	rest := strings.TrimPrefix(expr, "$")
+
+	for rest != "" {
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			return nil, errors.New("recursive descent is not supported")
+
+		case rest[0] == '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+
+			key := rest[:end]
+			rest = rest[end:]
+
+			switch key {
+			case "":
+				return nil, errors.New("empty key")
+			case "*":
+				segments = append(segments, segment{Wildcard: true})
+			default:
+				segments = append(segments, segment{Key: key})
+			}
+
+		case rest[0] == '[':
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, errors.New("missing ]")
+			}
+
+			inner := strings.TrimSpace(rest[1:end])
+			rest = rest[end+1:]
+
+			switch {
+			case inner == "*":
+				segments = append(segments, segment{Wildcard: true})
+			case len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0]:
+				segments = append(segments, segment{Key: inner[1 : len(inner)-1]})
+			default:
+				index, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid index: %v", inner)
+				}
+
+				segments = append(segments, segment{Index: index, IsIndex: true})
+			}
+
+		default:
+			return nil, fmt.Errorf("unexpected character: %q", rest[0])
+		}
+	}
+
+	return segments, nil
+}
+
+// evaluate applies the segments to the given nodes.
+func evaluate(nodes []interface{}, segments []segment) []interface{} {
+	for _, s := range segments {
+		next := make([]interface{}, 0)
+
+		for _, node := range nodes {
+			switch n := node.(type) {
+			case map[string]interface{}:
+				switch {
+				case s.Wildcard:
+					keys := make([]string, 0, len(n))
+					for k := range n {
+						keys = append(keys, k)
+					}
+
+					sort.Strings(keys)
+					for _, k := range keys {
+						next = append(next, n[k])
+					}
+				case !s.IsIndex:
+					if v, ok := n[s.Key]; ok {
+						next = append(next, v)
+					}
+				}
+
+			case []interface{}:
+				switch {
+				case s.Wildcard:
+					next = append(next, n...)
+				case s.IsIndex:
+					index := s.Index
+					if index < 0 {
+						index += len(n)
+					}
+
+					if index >= 0 && index < len(n) {
+						next = append(next, n[index])
+					}
+				}
+			}
+		}
+
+		nodes = next
+	}
+
+	return nodes
+}
+
+// values converts the scalar nodes to strings, arrays of scalars are flattened.
+func values(nodes []interface{}) []string {
+	result := make([]string, 0)
+
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case string:
+			if n != "" {
+				result = append(result, n)
+			}
+		case float64, bool:
+			result = append(result, fmt.Sprint(n))
+		case []interface{}:
+			result = append(result, values(n)...)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,240 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/hlog"
+
+	"github.com/cloudbox/autoscan"
+)
+
+type Config struct {
+	Name      string             `yaml:"name"`
+	Priority  int                `yaml:"priority"`
+	Event     string             `yaml:"event"`
+	Paths     []string           `yaml:"paths"`
+	Dirname   bool               `yaml:"dirname"`
+	Include   []string           `yaml:"include"`
+	Exclude   []string           `yaml:"exclude"`
+	Rewrite   []autoscan.Rewrite `yaml:"rewrite"`
+	Verbosity string             `yaml:"verbosity"`
+	Events    []Event            `yaml:"events"`
+}
+
+// An Event overrides the trigger-wide configuration for a single event type.
+type Event struct {
+	Name     string   `yaml:"name"`
+	Priority *int     `yaml:"priority"`
+	Paths    []string `yaml:"paths"`
+	Dirname  *bool    `yaml:"dirname"`
+}
+
+// New creates an autoscan-compatible HTTP Trigger for arbitrary JSON webhooks.
+// The paths to scan, and optionally the event type, are extracted with JSONPath expressions or Go templates.
+func New(c Config) (autoscan.HTTPTrigger, error) {
+	rewriter, err := autoscan.NewRewriter(c.Rewrite)
+	if err != nil {
+		return nil, err
+	}
+
+	filterer, err := autoscan.NewFilterer(c.Include, c.Exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	defaults, err := newRule(c.Priority, c.Paths, c.Dirname)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", c.Name, err)
+	}
+
+	var eventType extractor
+	if c.Event != "" {
+		eventType, err = newExtractor(c.Event)
+		if err != nil {
+			return nil, fmt.Errorf("%v: event: %w", c.Name, err)
+		}
+	}
+
+	// event specific configuration, overriding the trigger-wide defaults
+	events := make(map[string]*rule)
+	for _, e := range c.Events {
+		if eventType == nil {
+			return nil, fmt.Errorf("%v: events require an event expression", c.Name)
+		}
+
+		ev := *defaults
+		if e.Priority != nil {
+			ev.priority = *e.Priority
+		}
+
+		if e.Dirname != nil {
+			ev.dirname = *e.Dirname
+		}
+
+		if len(e.Paths) > 0 {
+			override, err := newRule(ev.priority, e.Paths, ev.dirname)
+			if err != nil {
+				return nil, fmt.Errorf("%v: %v: %w", c.Name, e.Name, err)
+			}
+
+			ev.paths = override.paths
+		}
+
+		events[strings.ToLower(e.Name)] = &ev
+	}
+
+	if len(defaults.paths) == 0 && len(events) == 0 {
+		return nil, fmt.Errorf("%v: no path expressions given", c.Name)
+	}
+
+	trigger := func(callback autoscan.ProcessorFunc) http.Handler {
+		return handler{
+			callback:  callback,
+			rewrite:   rewriter,
+			allowed:   filterer,
+			eventType: eventType,
+			defaults:  defaults,
+			events:    events,
+		}
+	}
+
+	return trigger, nil
+}
+
+type rule struct {
+	priority int
+	paths    []extractor
+	dirname  bool
+}
+
+func newRule(priority int, expressions []string, dirname bool) (*rule, error) {
+	r := &rule{
+		priority: priority,
+		paths:    make([]extractor, 0, len(expressions)),
+		dirname:  dirname,
+	}
+
+	for _, expr := range expressions {
+		ex, err := newExtractor(expr)
+		if err != nil {
+			return nil, fmt.Errorf("paths: %w", err)
+		}
+
+		r.paths = append(r.paths, ex)
+	}
+
+	return r, nil
+}
+
+type handler struct {
+	rewrite   autoscan.Rewriter
+	allowed   autoscan.Filterer
+	callback  autoscan.ProcessorFunc
+	eventType extractor
+	defaults  *rule
+	events    map[string]*rule
+}
+
+func (h handler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	var err error
+	rlog := hlog.FromRequest(r)
+
+	var body interface{}
+	err = json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		rlog.Error().Err(err).Msg("Failed decoding request")
+		rw.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	rlog.Trace().Interface("body", body).Msg("Received JSON body")
+
+	// determine event
+	ev := h.defaults
+	eventType := ""
+
+	if h.eventType != nil {
+		types, err := h.eventType(body)
+		if err != nil {
+			rlog.Error().Err(err).Msg("Failed extracting event type")
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if len(types) > 0 {
+			eventType = types[0]
+		}
+
+		// only the configured events are scanned when events are given
+		if len(h.events) > 0 {
+			configured, ok := h.events[strings.ToLower(eventType)]
+			if !ok {
+				rlog.Debug().Str("event", eventType).Msg("Ignoring event")
+				rw.WriteHeader(http.StatusOK)
+				return
+			}
+
+			ev = configured
+		}
+	}
+
+	// extract paths
+	unique := make(map[string]bool)
+	scans := make([]autoscan.Scan, 0)
+
+	for _, extract := range ev.paths {
+		paths, err := extract(body)
+		if err != nil {
+			rlog.Error().Err(err).Msg("Failed extracting paths")
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		for _, p := range paths {
+			if ev.dirname {
+				p = path.Dir(p)
+			}
+
+			folderPath := h.rewrite(path.Clean(p))
+			if unique[folderPath] || !h.allowed(folderPath) {
+				continue
+			}
+
+			// add scan
+			unique[folderPath] = true
+			scans = append(scans, autoscan.Scan{
+				Folder:   folderPath,
+				Priority: ev.priority,
+				Time:     now(),
+			})
+		}
+	}
+
+	if len(scans) == 0 {
+		rlog.Debug().Str("event", eventType).Msg("No paths to scan")
+		rw.WriteHeader(http.StatusOK)
+		return
+	}
+
+	err = h.callback(scans...)
+	if err != nil {
+		rlog.Error().Err(err).Msg("Processor could not process scans")
+		rw.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	rw.WriteHeader(http.StatusOK)
+	for _, scan := range scans {
+		rlog.Info().
+			Str("path", scan.Folder).
+			Str("event", eventType).
+			Msg("Scan moved to processor")
+	}
+}
+
+var now = time.Now
@@ -0,0 +1,251 @@
+package webhook
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cloudbox/autoscan"
+)
+
+func TestHandler(t *testing.T) {
+	type Given struct {
+		Config  Config
+		Fixture string
+	}
+
+	type Expected struct {
+		Scans      []autoscan.Scan
+		StatusCode int
+	}
+
+	type Test struct {
+		Name     string
+		Given    Given
+		Expected Expected
+	}
+
+	rewrite := []autoscan.Rewrite{{
+		From: "^/data/",
+		To:   "/mnt/unionfs/Media/",
+	}}
+
+	eventConfig := Config{
+		Name:     "webhook",
+		Priority: 2,
+		Event:    "$.type",
+		Paths:    []string{"$.item.files[*].path"},
+		Dirname:  true,
+		Exclude:  []string{`\.nfo$`},
+		Rewrite:  rewrite,
+	}
+
+	deletePriority := 8
+	eventConfig.Events = []Event{
+		{Name: "Import"},
+		{Name: "Delete", Priority: &deletePriority, Paths: []string{"{{ .item.folder }}"}, Dirname: new(bool)},
+	}
+
+	currentTime := time.Now()
+	now = func() time.Time {
+		return currentTime
+	}
+
+	var testCases = []Test{
+		{
+			"Extracts the folders of all files with JSONPath",
+			Given{
+				Config:  eventConfig,
+				Fixture: "testdata/import.json",
+			},
+			Expected{
+				StatusCode: 200,
+				Scans: []autoscan.Scan{
+					{
+						Folder:   "/mnt/unionfs/Media/TV/Westworld/Season 1",
+						Priority: 2,
+						Time:     currentTime,
+					},
+					{
+						Folder:   "/mnt/unionfs/Media/TV/Westworld/Season 2",
+						Priority: 2,
+						Time:     currentTime,
+					},
+				},
+			},
+		},
+		{
+			"Uses the event specific template and priority",
+			Given{
+				Config:  eventConfig,
+				Fixture: "testdata/delete.json",
+			},
+			Expected{
+				StatusCode: 200,
+				Scans: []autoscan.Scan{
+					{
+						Folder:   "/mnt/unionfs/Media/Movies/Interstellar (2014)",
+						Priority: 8,
+						Time:     currentTime,
+					},
+				},
+			},
+		},
+		{
+			"Returns 200 on events which are not configured without emitting a scan",
+			Given{
+				Config:  eventConfig,
+				Fixture: "testdata/health.json",
+			},
+			Expected{
+				StatusCode: 200,
+			},
+		},
+		{
+			"Template with multiple paths",
+			Given{
+				Config: Config{
+					Name:     "webhook",
+					Priority: 5,
+					Paths:    []string{`{{ range .item.files }}{{ dir .path }}{{ "\n" }}{{ end }}`},
+					Rewrite:  rewrite,
+				},
+				Fixture: "testdata/import.json",
+			},
+			Expected{
+				StatusCode: 200,
+				Scans: []autoscan.Scan{
+					{
+						Folder:   "/mnt/unionfs/Media/TV/Westworld/Season 1",
+						Priority: 5,
+						Time:     currentTime,
+					},
+					{
+						Folder:   "/mnt/unionfs/Media/TV/Westworld/Season 2",
+						Priority: 5,
+						Time:     currentTime,
+					},
+				},
+			},
+		},
+		{
+			"Returns 200 when no paths are found without emitting a scan",
+			Given{
+				Config: Config{
+					Name:  "webhook",
+					Paths: []string{"$.item.folder"},
+				},
+				Fixture: "testdata/health.json",
+			},
+			Expected{
+				StatusCode: 200,
+			},
+		},
+		{
+			"Returns bad request on invalid JSON",
+			Given{
+				Config:  eventConfig,
+				Fixture: "testdata/invalid.json",
+			},
+			Expected{
+				StatusCode: 400,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			callback := func(scans ...autoscan.Scan) error {
+				if !reflect.DeepEqual(tc.Expected.Scans, scans) {
+					t.Log(scans)
+					t.Log(tc.Expected.Scans)
+					t.Errorf("Scans do not equal")
+					return errors.New("Scans do not equal")
+				}
+
+				return nil
+			}
+
+			trigger, err := New(tc.Given.Config)
+			if err != nil {
+				t.Fatalf("Could not create Webhook Trigger: %v", err)
+			}
+
+			server := httptest.NewServer(trigger(callback))
+			defer server.Close()
+
+			request, err := os.Open(tc.Given.Fixture)
+			if err != nil {
+				t.Fatalf("Could not open the fixture: %s", tc.Given.Fixture)
+			}
+
+			res, err := http.Post(server.URL, "application/json", request)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+
+			defer res.Body.Close()
+			if res.StatusCode != tc.Expected.StatusCode {
+				t.Errorf("Status codes do not match: %d vs %d", res.StatusCode, tc.Expected.StatusCode)
+			}
+		})
+	}
+}
+
+func TestParseJSONPath(t *testing.T) {
+	type Test struct {
+		Name     string
+		Expr     string
+		Expected []segment
+		Error    bool
+	}
+
+	var testCases = []Test{
+		{
+			Name:     "Dot notation",
+			Expr:     "$.series.path",
+			Expected: []segment{{Key: "series"}, {Key: "path"}},
+		},
+		{
+			Name:     "Bracket notation with wildcard and index",
+			Expr:     "$['episodeFiles'][*].path[-1]",
+			Expected: []segment{{Key: "episodeFiles"}, {Wildcard: true}, {Key: "path"}, {Index: -1, IsIndex: true}},
+		},
+		{
+			Name:  "Recursive descent is not supported",
+			Expr:  "$..path",
+			Error: true,
+		},
+		{
+			Name:  "Unterminated bracket",
+			Expr:  "$.files[0",
+			Error: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			segments, err := parseJSONPath(tc.Expr)
+			if tc.Error {
+				if err == nil {
+					t.Errorf("Expected an error, got: %v", segments)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(segments, tc.Expected) {
+				t.Logf("want: %v", tc.Expected)
+				t.Logf("got:  %v", segments)
+				t.Errorf("Segments do not equal")
+			}
+		})
+	}
+}